@@ -1,11 +1,37 @@
 package ast
 
-import "time"
+import (
+	"math/big"
+	"time"
+
+	"github.com/zac-garby/booleang/token"
+)
 
 // A Node is the interface from which both expression types
 // and statement types extend.
 type Node interface {
 	String() string
+
+	// Range returns the range of source code the node was
+	// parsed from.
+	Range() token.Range
+
+	// Pos returns the position the node starts at.
+	Pos() token.Position
+}
+
+// A Span is the range of source code a node was parsed from. It's
+// embedded in every node to implement Range and Pos.
+type Span token.Range
+
+// Range returns the span as a token.Range.
+func (s Span) Range() token.Range {
+	return token.Range(s)
+}
+
+// Pos returns the start of the span.
+func (s Span) Pos() token.Position {
+	return s.Start
 }
 
 // A Statement is a piece of code which doesn't evaluate to
@@ -22,10 +48,33 @@ type Expression interface {
 	Expression()
 }
 
-// A Parameter is either a macro or an identifier.
+// An Int is an integer expression, such as the width of a bus,
+// which is evaluated when a circuit is elaborated.
+type Int interface {
+	Node
+	Int()
+}
+
+// A Parameter is either a macro or an identifier. An identifier
+// can refer to a single register, a whole bus, or part of a bus.
+//
+// In a circuit's signature, Width is the width of a bus parameter,
+// e.g. a[8], or nil for a single bit. Elsewhere, if Indexed is set,
+// the parameter refers to the bits High down to Low of a bus, e.g.
+// s[7:4], or s[3] where High and Low are both 3.
+//
+// Outside of a signature, an identifier can have a Suffix, whose
+// value is appended to its name, so c<i+1> is c3 when i is 2.
 type Parameter struct {
-	Macro bool
-	Name  string
+	Span
+	Macro  bool
+	Name   string
+	Suffix Int
+
+	Width Int
+
+	Indexed   bool
+	High, Low Int
 }
 
 // Parameters are a list of strings.
@@ -34,15 +83,22 @@ type Parameters []Parameter
 // A Circuit is similar to a function in other languages - it
 // is a bit of code you can call upon later. A Circuit is neither
 // a statement or an expression.
+//
+// A circuit can be generic over some integers, which are given
+// when it's called, and can be used in the widths of its buses.
+// e.g. circuit addN<N> (a[N], b[N]) -> (s[N], carry) { ... }
 type Circuit struct {
+	Span
 	Name            string
-	Inputs, Outputs []string
+	Generics        []string
+	Inputs, Outputs Parameters
 	Statements      []Statement
 }
 
 // An Include represents a file included into a Program. A file
 // can be included either by name or path.
 type Include struct {
+	Span
 	ByName bool
 	Value  string
 }
@@ -54,6 +110,7 @@ type Include struct {
 // A Program also contains a list of includes, in order
 // of their lexical position.
 type Program struct {
+	Span
 	Name     string
 	Includes []Include
 	Circuits []*Circuit
@@ -68,34 +125,64 @@ type (
 	// e.g. %num (a0, a1, a2, a3);
 	MacroStmt struct {
 		*stmt
+		Span
 		Name      string
 		Registers Parameters
 	}
 
+	// A BusStmt declares a bus of registers.
+	// e.g. data[8];
+	BusStmt struct {
+		*stmt
+		Span
+		Name  string
+		Width Int
+	}
+
 	// A Call statement calls a circuit.
 	// e.g. add (a, b, 0) -> (d, e);
 	Call struct {
 		*stmt
-		Circuit string
-		Inputs  []Expression
-		Outputs Parameters
+		Span
+		Circuit  string
+		Generics []Int
+		Inputs   []Expression
+		Outputs  Parameters
 	}
 
 	// A Pipe statement pipes expressions into registers.
 	// e.g. (0, x) -> (a, b);
 	Pipe struct {
 		*stmt
+		Span
 		Inputs  []Expression
 		Outputs Parameters
 	}
 
+	// A For repeats its body once for each integer from From up
+	// to, but not including, To. The body is repeated when the
+	// circuit is elaborated, with Var set to each integer in turn.
+	// e.g. for i in 0..N { adder (a[i], b[i], c[i]) -> (s[i], c[i+1]); }
+	For struct {
+		*stmt
+		Span
+		Var      string
+		From, To Int
+		Body     []Statement
+	}
+
 	// A Clock executes some statements with a set interval.
-	// Doesn't need a semi colon.
+	// Doesn't need a semi colon. The optional counter is a
+	// macro which counts the clock's ticks, and is Width bits
+	// wide - if Width is 0, a default width is used.
 	// e.g. clock 1.5s { !a -> a; }
+	// e.g. clock 1s %tick[4] { onum (%tick); }
 	Clock struct {
 		*stmt
+		Span
 		Delay   time.Duration
 		Counter string
+		Width   int
 		Body    []Statement
 	}
 )
@@ -108,20 +195,37 @@ type (
 	// A Bit is a bit literal - either 1 or 0.
 	Bit struct {
 		*expr
+		Span
 		Value bool
 	}
 
-	// An Identifier usually denotes the name of a register.
-	// e.g. foobar
+	// A Number is a constant which can be more than one bit wide.
+	// Its Width is the number of bits it's written with, or 0 if
+	// it's unsized, in which case its width depends on where it's
+	// used. Negative numbers are stored in two's complement.
+	// e.g. 200, 8'hFF
+	Number struct {
+		*expr
+		Span
+		Value *big.Int
+		Width int
+	}
+
+	// An Identifier usually denotes the name of a register. If it
+	// has a Suffix, the suffix's value is appended to its name.
+	// e.g. foobar, c<i+1>
 	Identifier struct {
 		*expr
-		Value string
+		Span
+		Value  string
+		Suffix Int
 	}
 
 	// An Infix is an infix expression.
 	// e.g. a ⊻ b
 	Infix struct {
 		*expr
+		Span
 		Left, Right Expression
 		Operator    string
 	}
@@ -130,6 +234,17 @@ type (
 	// e.g. !foo
 	Prefix struct {
 		*expr
+		Span
+		Right    Expression
+		Operator string
+	}
+
+	// A Reduction combines all the bits of an expression into one
+	// using a binary operator.
+	// e.g. &%a, ^b
+	Reduction struct {
+		*expr
+		Span
 		Right    Expression
 		Operator string
 	}
@@ -138,6 +253,98 @@ type (
 	// e.g. %a
 	MacroExpr struct {
 		*expr
+		Span
 		Name string
 	}
+
+	// An Index selects the bits High down to Low of a bus. The bit
+	// at Low is the least significant, so if Low is greater than
+	// High, the bits are reversed.
+	// e.g. a[3], a[7:4]
+	Index struct {
+		*expr
+		Span
+		Name      string
+		Suffix    Int
+		High, Low Int
+	}
+
+	// A Concat joins the bits of several expressions together,
+	// least significant first.
+	// e.g. {a, b[3:0], 1}
+	Concat struct {
+		*expr
+		Span
+		Parts []Expression
+	}
 )
+
+type integer struct{}
+
+func (i *integer) Int() {}
+
+type (
+	// A Const is a constant integer.
+	// e.g. 8
+	Const struct {
+		*integer
+		Span
+		Value int
+	}
+
+	// A Var is the value of one of a circuit's generic parameters.
+	// e.g. N
+	Var struct {
+		*integer
+		Span
+		Name string
+	}
+
+	// An Arith is an arithmetic operation on two integers. The
+	// operator is one of +, - and *.
+	// e.g. N+1, 2*N
+	Arith struct {
+		*integer
+		Span
+		Left, Right Int
+		Operator    string
+	}
+)
+
+// MinWidth returns the fewest bits the number can be stored in. A
+// negative number needs room for its sign bit.
+func (n *Number) MinWidth() int {
+	if n.Value.Sign() >= 0 {
+		if n.Value.BitLen() == 0 {
+			return 1
+		}
+
+		return n.Value.BitLen()
+	}
+
+	// -2^k fits in k+1 bits, so find the width of -n-1 instead.
+	return new(big.Int).Not(n.Value).BitLen() + 1
+}
+
+// Fits checks whether the number can be stored in the given number
+// of bits.
+func (n *Number) Fits(width int) bool {
+	return width >= n.MinWidth()
+}
+
+// Bits returns the bits of the number, least significant first,
+// padded or truncated to the given width.
+func (n *Number) Bits(width int) []bool {
+	value := n.Value
+
+	if value.Sign() < 0 {
+		value = new(big.Int).Add(value, new(big.Int).Lsh(big.NewInt(1), uint(width)))
+	}
+
+	bits := make([]bool, width)
+	for i := range bits {
+		bits[i] = value.Bit(i) == 1
+	}
+
+	return bits
+}
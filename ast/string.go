@@ -22,8 +22,9 @@ func (p *Program) String() string {
 
 func (c *Circuit) String() string {
 	return fmt.Sprintf(
-		`%s (%s) -> (%s) {%s}`,
+		`%s%s (%s) -> (%s) {%s}`,
 		c.Name,
+		generics(c.Generics),
 		c.Inputs,
 		c.Outputs,
 		stmts(c.Statements),
@@ -34,16 +35,49 @@ func (p Parameters) String() string {
 	var strs []string
 
 	for _, param := range p {
-		if param.Macro {
-			strs = append(strs, "%"+param.Name)
-		} else {
-			strs = append(strs, param.Name)
-		}
+		strs = append(strs, param.String())
 	}
 
 	return strings.Join(strs, ", ")
 }
 
+func (p Parameter) String() string {
+	switch {
+	case p.Macro:
+		return "%" + p.Name
+	case p.Width != nil:
+		return fmt.Sprintf("%s[%s]", p.Name, p.Width)
+	case p.Indexed:
+		return index(suffixed(p.Name, p.Suffix), p.High, p.Low)
+	}
+
+	return suffixed(p.Name, p.Suffix)
+}
+
+func suffixed(name string, suffix Int) string {
+	if suffix == nil {
+		return name
+	}
+
+	return fmt.Sprintf("%s<%s>", name, suffix)
+}
+
+func index(name string, high, low Int) string {
+	if high.String() == low.String() {
+		return fmt.Sprintf("%s[%s]", name, high)
+	}
+
+	return fmt.Sprintf("%s[%s:%s]", name, high, low)
+}
+
+func generics(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("<%s>", strings.Join(names, ", "))
+}
+
 func (i Include) String() string {
 	if i.ByName {
 		return fmt.Sprintf("<include name '%s'>", i.Value)
@@ -76,10 +110,21 @@ func (m *MacroStmt) String() string {
 	return fmt.Sprintf("<macro %s (%s)>", m.Name, m.Registers.String())
 }
 
+func (b *BusStmt) String() string {
+	return fmt.Sprintf("<bus %s[%s]>", b.Name, b.Width)
+}
+
 func (c *Call) String() string {
+	var args []string
+
+	for _, arg := range c.Generics {
+		args = append(args, arg.String())
+	}
+
 	return fmt.Sprintf(
-		"<call %s (%s) -> (%s)",
+		"<call %s%s (%s) -> (%s)",
 		c.Circuit,
+		generics(args),
 		exprs(c.Inputs),
 		c.Outputs.String(),
 	)
@@ -95,13 +140,24 @@ func (p *Pipe) String() string {
 
 func (c *Clock) String() string {
 	return fmt.Sprintf(
-		"<clock %s (%s) [%s]>",
+		"<clock %s (%s[%d]) [%s]>",
 		c.Delay.String(),
 		c.Counter,
+		c.Width,
 		stmts(c.Body),
 	)
 }
 
+func (f *For) String() string {
+	return fmt.Sprintf(
+		"<for %s in %s..%s [%s]>",
+		f.Var,
+		f.From,
+		f.To,
+		stmts(f.Body),
+	)
+}
+
 func (b *Bit) String() string {
 	if b.Value {
 		return "<bit 1>"
@@ -109,8 +165,16 @@ func (b *Bit) String() string {
 	return "<bit 0>"
 }
 
+func (n *Number) String() string {
+	if n.Width == 0 {
+		return n.Value.String()
+	}
+
+	return fmt.Sprintf("%d'd%s", n.Width, n.Value)
+}
+
 func (i *Identifier) String() string {
-	return i.Value
+	return suffixed(i.Value, i.Suffix)
 }
 
 func (i *Infix) String() string {
@@ -121,6 +185,46 @@ func (p *Prefix) String() string {
 	return fmt.Sprintf("%s%s", p.Operator, p.Right.String())
 }
 
+func (r *Reduction) String() string {
+	return fmt.Sprintf("%s%s", r.Operator, r.Right.String())
+}
+
 func (m *MacroExpr) String() string {
 	return fmt.Sprintf("%%%s", m.Name)
 }
+
+func (i *Index) String() string {
+	return index(suffixed(i.Name, i.Suffix), i.High, i.Low)
+}
+
+func (c *Concat) String() string {
+	return fmt.Sprintf("{%s}", exprs(c.Parts))
+}
+
+func (c *Const) String() string {
+	return fmt.Sprint(c.Value)
+}
+
+func (v *Var) String() string {
+	return v.Name
+}
+
+func (a *Arith) String() string {
+	left, right := a.Left.String(), a.Right.String()
+
+	// only multiplication needs its operands to be bracketed, since
+	// it binds more tightly than addition and subtraction.
+	if a.Operator == "*" {
+		if l, ok := a.Left.(*Arith); ok && l.Operator != "*" {
+			left = "(" + left + ")"
+		}
+
+		if r, ok := a.Right.(*Arith); ok && r.Operator != "*" {
+			right = "(" + right + ")"
+		}
+	} else if r, ok := a.Right.(*Arith); ok && r.Operator != "*" {
+		right = "(" + right + ")"
+	}
+
+	return fmt.Sprintf("%s%s%s", left, a.Operator, right)
+}
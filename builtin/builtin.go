@@ -0,0 +1,112 @@
+package builtin
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// A Kind specifies what a builtin does when it's called.
+type Kind int
+
+const (
+	// Output builtins persistently display the values of their
+	// inputs, which are updated whenever they change.
+	Output Kind = iota
+
+	// Input builtins pause execution until a value has been read
+	// into their outputs.
+	Input
+)
+
+// A Builtin is a circuit implemented by booleang itself, rather than
+// in booleang code. Calls to a circuit which isn't defined in a
+// program are resolved against the builtins.
+type Builtin struct {
+	Name string
+	Kind Kind
+
+	// Format renders the inputs of an output builtin, which are
+	// given least significant bit first.
+	Format func(bits []bool) string
+}
+
+var builtins = map[string]*Builtin{}
+
+func init() {
+	register(&Builtin{Name: "obit", Kind: Output, Format: formatBits})
+	register(&Builtin{Name: "onumu", Kind: Output, Format: formatUnsigned})
+	register(&Builtin{Name: "onums", Kind: Output, Format: formatSigned})
+
+	// onum and oint are aliases of onumu
+	register(&Builtin{Name: "onum", Kind: Output, Format: formatUnsigned})
+	register(&Builtin{Name: "oint", Kind: Output, Format: formatUnsigned})
+
+	register(&Builtin{Name: "input", Kind: Input})
+}
+
+func register(b *Builtin) {
+	builtins[b.Name] = b
+}
+
+// Lookup finds the builtin with the given name.
+func Lookup(name string) (b *Builtin, ok bool) {
+	b, ok = builtins[name]
+	return b, ok
+}
+
+// Names returns the names of every builtin, in alphabetical order.
+func Names() []string {
+	var names []string
+
+	for name := range builtins {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// formatBits renders each bit in the order they were given, e.g. "0 1 1".
+func formatBits(bits []bool) string {
+	strs := make([]string, len(bits))
+
+	for i, bit := range bits {
+		if bit {
+			strs[i] = "1"
+		} else {
+			strs[i] = "0"
+		}
+	}
+
+	return strings.Join(strs, " ")
+}
+
+// formatUnsigned interprets the bits as an unsigned integer.
+func formatUnsigned(bits []bool) string {
+	return unsigned(bits).String()
+}
+
+// formatSigned interprets the bits as a two's complement integer.
+func formatSigned(bits []bool) string {
+	n := unsigned(bits)
+
+	if len(bits) > 0 && bits[len(bits)-1] {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(bits))))
+	}
+
+	return n.String()
+}
+
+func unsigned(bits []bool) *big.Int {
+	n := new(big.Int)
+
+	for i, bit := range bits {
+		if bit {
+			n.SetBit(n, i, 1)
+		}
+	}
+
+	return n
+}
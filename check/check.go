@@ -0,0 +1,589 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zac-garby/booleang/ast"
+	"github.com/zac-garby/booleang/builtin"
+	"github.com/zac-garby/booleang/netlist"
+	"github.com/zac-garby/booleang/token"
+)
+
+// Program checks a program for mistakes which would stop it from
+// being elaborated, such as calls to undefined circuits, and
+// returns every problem it finds.
+func Program(prog *ast.Program) []error {
+	return program(prog, true)
+}
+
+// Circuits checks every circuit in a program like Program does, but
+// doesn't need the program to have a 'main' circuit, since it might
+// be elaborated starting at a different one.
+func Circuits(prog *ast.Program) []error {
+	return program(prog, false)
+}
+
+func program(prog *ast.Program, needMain bool) []error {
+	c := &checker{
+		circuits: make(map[string]*ast.Circuit),
+		calls:    make(map[string][]*ast.Call),
+	}
+
+	for _, circ := range prog.Circuits {
+		if _, ok := c.circuits[circ.Name]; ok {
+			c.err(circ.Range(), "circuit '%s' is defined more than once", circ.Name)
+			continue
+		}
+
+		c.circuits[circ.Name] = circ
+	}
+
+	if _, ok := c.circuits[netlist.Main]; needMain && !ok {
+		c.err(prog.Range(), "there is no '%s' circuit to start at", netlist.Main)
+	}
+
+	for _, circ := range prog.Circuits {
+		if c.circuits[circ.Name] != circ {
+			continue
+		}
+
+		s := newScope(c, circ)
+
+		// the values of the circuit's generic parameters aren't
+		// known until it's called.
+		for _, name := range circ.Generics {
+			s.ints[name] = -1
+		}
+
+		for _, param := range append(append(ast.Parameters{}, circ.Inputs...), circ.Outputs...) {
+			if param.Width != nil {
+				s.buses[param.Name] = s.busWidth(param.Width)
+			}
+		}
+
+		s.statements(circ.Statements)
+	}
+
+	c.recursion(prog)
+
+	return c.errors
+}
+
+type checker struct {
+	circuits map[string]*ast.Circuit
+	errors   []error
+
+	// calls maps each circuit to the user-defined circuits it
+	// calls, in the order they're first called.
+	calls map[string][]*ast.Call
+}
+
+func (c *checker) err(r token.Range, msg string, format ...interface{}) {
+	c.errors = append(c.errors, &Error{
+		Message: fmt.Sprintf(msg, format...),
+		Range:   r,
+	})
+}
+
+// recursion reports each cycle in the call graph, since circuits
+// can't be instantiated recursively.
+func (c *checker) recursion(prog *ast.Program) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var (
+		state = make(map[string]int)
+		path  []string
+		visit func(name string)
+	)
+
+	visit = func(name string) {
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, call := range c.calls[name] {
+			callee := call.Circuit
+
+			switch state[callee] {
+			case visiting:
+				var start int
+				for i, n := range path {
+					if n == callee {
+						start = i
+					}
+				}
+
+				cycle := append(append([]string{}, path[start:]...), callee)
+				c.err(
+					call.Range(),
+					"circuit '%s' instantiates itself recursively: %s",
+					callee, strings.Join(cycle, " -> "),
+				)
+
+			case unvisited:
+				visit(callee)
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+	}
+
+	for _, circ := range prog.Circuits {
+		if state[circ.Name] == unvisited {
+			visit(circ.Name)
+		}
+	}
+}
+
+// A scope keeps track of the width of each macro and bus visible
+// at some point in a circuit, and the values of the circuit's
+// generic parameters. A value of -1 means it isn't known, either
+// because of an error which has already been reported, or because
+// it depends on a generic parameter.
+type scope struct {
+	*checker
+	circuit *ast.Circuit
+	macros  map[string]int
+	buses   map[string]int
+	ints    map[string]int
+}
+
+func newScope(c *checker, circ *ast.Circuit) *scope {
+	return &scope{
+		checker: c,
+		circuit: circ,
+		macros:  make(map[string]int),
+		buses:   make(map[string]int),
+		ints:    make(map[string]int),
+	}
+}
+
+func (s *scope) statements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		s.statement(stmt)
+	}
+}
+
+func (s *scope) statement(stmt ast.Statement) {
+	switch st := stmt.(type) {
+	case *ast.MacroStmt:
+		s.macros[st.Name] = s.params(st.Registers)
+
+	case *ast.BusStmt:
+		if _, ok := s.buses[st.Name]; ok {
+			s.err(st.Range(), "bus '%s' is already declared", st.Name)
+		}
+
+		s.buses[st.Name] = s.busWidth(st.Width)
+
+	case *ast.Pipe:
+		out := s.params(st.Outputs)
+
+		// a single unsized number is as wide as the registers it's
+		// piped into.
+		if len(st.Inputs) == 1 && unsized(st.Inputs[0]) {
+			s.sized(st.Inputs[0], out)
+			break
+		}
+
+		in := s.exprs(st.Inputs)
+		if in >= 0 && out >= 0 && in != out {
+			s.err(st.Range(), "cannot pipe %d bits into %d registers", in, out)
+		}
+
+	case *ast.Call:
+		s.call(st)
+
+	case *ast.Clock:
+		body := s.sub()
+
+		if st.Counter != "" {
+			if st.Width == 0 {
+				body.macros[st.Counter] = netlist.CounterWidth
+			} else {
+				body.macros[st.Counter] = st.Width
+			}
+		}
+
+		if st.Delay <= 0 {
+			s.err(st.Range(), "a clock's period must be positive. got %s", st.Delay)
+		}
+
+		body.statements(st.Body)
+
+	case *ast.For:
+		s.integer(st.From)
+		s.integer(st.To)
+
+		// the body is only checked once, without knowing the value
+		// of the loop variable. like its macros, the buses declared
+		// inside the loop aren't visible after it.
+		body := s.sub()
+		body.ints[st.Var] = -1
+
+		body.statements(st.Body)
+	}
+}
+
+// sub makes a scope for a block inside this scope, such as the body
+// of a clock, which starts with the same macros, buses and integers.
+func (s *scope) sub() *scope {
+	body := newScope(s.checker, s.circuit)
+
+	for name, width := range s.macros {
+		body.macros[name] = width
+	}
+
+	for name, width := range s.buses {
+		body.buses[name] = width
+	}
+
+	for name, value := range s.ints {
+		body.ints[name] = value
+	}
+
+	return body
+}
+
+func (s *scope) call(c *ast.Call) {
+	in, out := s.exprs(c.Inputs), s.params(c.Outputs)
+
+	circ, ok := s.circuits[c.Circuit]
+	if !ok {
+		b, ok := builtin.Lookup(c.Circuit)
+		if !ok {
+			s.err(c.Range(), "circuit '%s' is not defined", c.Circuit)
+			return
+		}
+
+		switch b.Kind {
+		case builtin.Output:
+			if len(c.Outputs) > 0 {
+				s.err(c.Range(), "the builtin '%s' doesn't have any outputs", b.Name)
+			}
+
+		case builtin.Input:
+			if len(c.Inputs) > 0 {
+				s.err(c.Range(), "the builtin '%s' doesn't take any inputs", b.Name)
+			}
+
+			if out == 0 {
+				s.err(c.Range(), "the builtin '%s' needs at least one output", b.Name)
+			}
+		}
+
+		return
+	}
+
+	s.called(c)
+
+	if len(c.Generics) != len(circ.Generics) {
+		s.err(c.Range(), "circuit '%s' takes %d generic parameters, but %d were given", circ.Name, len(circ.Generics), len(c.Generics))
+		return
+	}
+
+	// the callee's signature is evaluated with a separate checker,
+	// since any mistakes in it are reported when the callee itself
+	// is checked.
+	callee := newScope(&checker{}, circ)
+
+	for i, name := range circ.Generics {
+		callee.ints[name] = s.integer(c.Generics[i])
+	}
+
+	if n := callee.widths(circ.Inputs); in >= 0 && n >= 0 && in != n {
+		s.err(c.Range(), "circuit '%s' takes %d inputs, but %d were given", circ.Name, n, in)
+	}
+
+	if n := callee.widths(circ.Outputs); out >= 0 && n >= 0 && out != n {
+		s.err(c.Range(), "circuit '%s' has %d outputs, but %d were given", circ.Name, n, out)
+	}
+}
+
+// widths returns the total width of the parameters in a circuit's
+// signature.
+func (s *scope) widths(params ast.Parameters) int {
+	total := 0
+
+	for _, param := range params {
+		width := 1
+		if param.Width != nil {
+			width = s.busWidth(param.Width)
+		}
+
+		if width < 0 || total < 0 {
+			total = -1
+		} else {
+			total += width
+		}
+	}
+
+	return total
+}
+
+// busWidth evaluates the width of a bus, reporting an error if it
+// isn't positive.
+func (s *scope) busWidth(n ast.Int) int {
+	width := s.integer(n)
+
+	if width == 0 || width < -1 {
+		s.err(n.Range(), "the width of a bus must be positive, but %s is %d", n, width)
+		return -1
+	}
+
+	return width
+}
+
+// integer evaluates an integer expression, returning -1 if its
+// value isn't known.
+func (s *scope) integer(n ast.Int) int {
+	switch in := n.(type) {
+	case *ast.Const:
+		return in.Value
+
+	case *ast.Var:
+		value, ok := s.ints[in.Name]
+		if !ok {
+			s.err(in.Range(), "'%s' is not a generic parameter or loop variable in '%s'", in.Name, s.circuit.Name)
+			return -1
+		}
+
+		return value
+
+	case *ast.Arith:
+		left, right := s.integer(in.Left), s.integer(in.Right)
+		if left < 0 || right < 0 {
+			return -1
+		}
+
+		switch in.Operator {
+		case "+":
+			return left + right
+		case "-":
+			return left - right
+		case "*":
+			return left * right
+		}
+	}
+
+	return -1
+}
+
+// called records that the scope's circuit calls another circuit.
+func (s *scope) called(c *ast.Call) {
+	for _, call := range s.calls[s.circuit.Name] {
+		if call.Circuit == c.Circuit {
+			return
+		}
+	}
+
+	s.calls[s.circuit.Name] = append(s.calls[s.circuit.Name], c)
+}
+
+// macro returns the width of a macro, reporting an error at the
+// given range if it isn't defined.
+func (s *scope) macro(name string, r token.Range) int {
+	width, ok := s.macros[name]
+	if !ok {
+		s.err(r, "macro '%%%s' is not defined", name)
+		return -1
+	}
+
+	return width
+}
+
+// name resolves the name of a register or bus, appending the value
+// of its suffix if it has one. If the suffix's value isn't known,
+// neither is the name, so it returns an empty string.
+func (s *scope) name(name string, suffix ast.Int) string {
+	if suffix == nil {
+		return name
+	}
+
+	n := s.integer(suffix)
+	if n < 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s%d", name, n)
+}
+
+// bus returns the width of a bus, or 1 if there's no bus with the
+// given name, since it refers to a single register instead. If the
+// name isn't known, neither is the width.
+func (s *scope) bus(name string) int {
+	if name == "" {
+		return -1
+	}
+
+	if width, ok := s.buses[name]; ok {
+		return width
+	}
+
+	return 1
+}
+
+// slice returns the width of part of a bus, reporting an error at
+// the given range if the bus isn't declared or is too narrow.
+func (s *scope) slice(name string, hi, lo ast.Int, r token.Range) int {
+	if name == "" {
+		return -1
+	}
+
+	width, ok := s.buses[name]
+	if !ok {
+		s.err(r, "'%s' is not a bus, so it can't be indexed", name)
+		return -1
+	}
+
+	// a single bit is parsed with the same integer for both ends,
+	// which should only be evaluated once.
+	high, low := s.integer(hi), 0
+	if lo == hi {
+		low = high
+	} else {
+		low = s.integer(lo)
+	}
+
+	if high < 0 || low < 0 {
+		return -1
+	}
+
+	if width >= 0 && (high >= width || low >= width) {
+		s.err(r, "%s is out of range for the %d-bit bus '%s'", (&ast.Index{Name: name, High: hi, Low: lo}).String(), width, name)
+		return -1
+	}
+
+	if high < low {
+		return low - high + 1
+	}
+
+	return high - low + 1
+}
+
+// params returns the total width of a list of parameters.
+func (s *scope) params(params ast.Parameters) int {
+	total := 0
+
+	for _, param := range params {
+		var width int
+
+		switch {
+		case param.Macro:
+			width = s.macro(param.Name, param.Range())
+		case param.Indexed:
+			width = s.slice(s.name(param.Name, param.Suffix), param.High, param.Low, param.Range())
+		default:
+			width = s.bus(s.name(param.Name, param.Suffix))
+		}
+
+		if width < 0 || total < 0 {
+			total = -1
+		} else {
+			total += width
+		}
+	}
+
+	return total
+}
+
+// exprs returns the total width of a list of expressions.
+func (s *scope) exprs(exprs []ast.Expression) int {
+	total := 0
+
+	for _, expr := range exprs {
+		width := s.width(expr)
+
+		if width < 0 || total < 0 {
+			total = -1
+		} else {
+			total += width
+		}
+	}
+
+	return total
+}
+
+// width returns the width of an expression, reporting any
+// mistakes inside it.
+func (s *scope) width(expr ast.Expression) int {
+	switch ex := expr.(type) {
+	case *ast.MacroExpr:
+		return s.macro(ex.Name, ex.Range())
+
+	case *ast.Identifier:
+		return s.bus(s.name(ex.Value, ex.Suffix))
+
+	case *ast.Index:
+		return s.slice(s.name(ex.Name, ex.Suffix), ex.High, ex.Low, ex.Range())
+
+	case *ast.Concat:
+		return s.exprs(ex.Parts)
+
+	case *ast.Prefix:
+		return s.width(ex.Right)
+
+	case *ast.Reduction:
+		if s.width(ex.Right) < 0 {
+			return -1
+		}
+
+	case *ast.Number:
+		if ex.Width == 0 {
+			return ex.MinWidth()
+		}
+
+		return ex.Width
+
+	case *ast.Infix:
+		var left, right int
+
+		// an unsized number takes the width of the other operand.
+		if unsized(ex.Left) {
+			right = s.width(ex.Right)
+			left = s.sized(ex.Left, right)
+		} else {
+			left = s.width(ex.Left)
+			right = s.sized(ex.Right, left)
+		}
+
+		switch {
+		case left < 0 || right < 0:
+			return -1
+		case left == 1:
+			return right
+		case right == 1 || left == right:
+			return left
+		}
+
+		s.err(ex.Range(), "cannot apply %s to %d bits and %d bits", ex.Operator, left, right)
+		return -1
+	}
+
+	return 1
+}
+
+// sized returns the width of an expression, giving it the width of
+// its context if it's an unsized number.
+func (s *scope) sized(expr ast.Expression, width int) int {
+	if !unsized(expr) || width < 0 {
+		return s.width(expr)
+	}
+
+	if n := expr.(*ast.Number); !n.Fits(width) {
+		s.err(n.Range(), "%s doesn't fit in %d bits", n, width)
+		return -1
+	}
+
+	return width
+}
+
+func unsized(expr ast.Expression) bool {
+	n, ok := expr.(*ast.Number)
+	return ok && n.Width == 0
+}
@@ -0,0 +1,145 @@
+package check_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/zac-garby/booleang/check"
+	"github.com/zac-garby/booleang/parser"
+)
+
+func TestValid(t *testing.T) {
+	input := `name: "test";
+circuit adder (a, b, cin) -> (sum, cout) {
+	((a ^ b) ^ cin) -> sum;
+	(((a ^ b) & cin) | (a & b)) -> cout;
+}
+
+circuit main {
+	%n (a, b);
+	input () -> (%n);
+	adder (%n, 0) -> (s, c);
+	obit (s, c);
+
+	clock 1s %tick[2] {
+		(%tick) -> %n;
+	}
+
+	y[3];
+	for i in 0..3 {
+		t[2];
+		(a, b) -> t;
+		(t[0] & t[1]) -> y[i];
+	}
+}
+`
+
+	prog, err := parser.New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range Program(prog) {
+		t.Error(err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	input := `name: "test";
+circuit f (a) -> (b) {
+	g (a) -> (b);
+	(a, a) -> b;
+	undefined (a) -> (b);
+	(%undefined) -> b;
+	!%m -> b;
+}
+
+circuit g (a) -> (b) {
+	f (a, a) -> (b);
+	f (a) -> (b, b);
+	obit (a) -> (b);
+	input (a) -> ();
+	clock 1s %t { (%t) -> b; }
+	(%t) -> b;
+}
+
+circuit g {}
+
+circuit h (a[4]) -> (b[2]) {
+	(a) -> b;
+	(a[4]) -> b[1];
+	(c[0]) -> b[0];
+	(a & b) -> b[1];
+	5 -> b;
+	(a | 20) -> a;
+}
+
+circuit generic<N> (a[N]) -> (b[N-1]) {
+	(a[N-1:1]) -> b;
+	(a[M]) -> b[0];
+}
+
+circuit i {
+	x[4];
+	generic (x) -> (x);
+	generic<4> (x) -> (x);
+
+	for j in 0..K {
+		(x[j], y<j>) -> (x[j+1], y<j+1>);
+	}
+}
+`
+
+	prog, err := parser.New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"circuit 'g' is defined more than once",
+		"there is no 'main' circuit",
+		"cannot pipe 2 bits into 1 registers",
+		"circuit 'undefined' is not defined",
+		"macro '%undefined' is not defined",
+		"macro '%m' is not defined",
+		"circuit 'f' takes 1 inputs, but 2 were given",
+		"circuit 'f' has 1 outputs, but 2 were given",
+		"the builtin 'obit' doesn't have any outputs",
+		"the builtin 'input' doesn't take any inputs",
+		"the builtin 'input' needs at least one output",
+		"cannot pipe 8 bits into 1 registers",
+		"macro '%t' is not defined",
+		"cannot pipe 4 bits into 2 registers",
+		"a[4] is out of range for the 4-bit bus 'a'",
+		"'c' is not a bus",
+		"cannot apply & to 4 bits and 2 bits",
+		"5 doesn't fit in 2 bits",
+		"20 doesn't fit in 4 bits",
+		"'M' is not a generic parameter or loop variable in 'generic'",
+		"circuit 'generic' takes 1 generic parameters, but 0 were given",
+		"circuit 'generic' has 3 outputs, but 4 were given",
+		"'K' is not a generic parameter or loop variable in 'i'",
+		"instantiates itself recursively: f -> g -> f",
+	}
+
+	errs := Program(prog)
+
+	if len(errs) != len(expected) {
+		t.Errorf("expected %d errors, got %d", len(expected), len(errs))
+		for _, err := range errs {
+			t.Log(err)
+		}
+	}
+
+	for i, exp := range expected {
+		if i < len(errs) && !strings.Contains(errs[i].Error(), exp) {
+			t.Errorf("(%d) expected an error containing %q, got %q", i, exp, errs[i])
+		}
+	}
+
+	// (a, a) -> b;
+	pos := errs[2].(*Error).Range
+	if pos.Start.Line != 4 || pos.Start.Col != 2 || pos.End.Col != 13 {
+		t.Errorf("wrong range for the pipe error: %s", pos)
+	}
+}
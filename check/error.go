@@ -0,0 +1,21 @@
+package check
+
+import (
+	"fmt"
+
+	"github.com/zac-garby/booleang/token"
+)
+
+// An Error represents a problem found while checking a program.
+type Error struct {
+	Message string
+	Range   token.Range
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf(
+		"-* Check Error @ [%s] *- %s",
+		e.Range,
+		e.Message,
+	)
+}
@@ -0,0 +1,129 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/zac-garby/booleang/sim"
+)
+
+// A Display shows the outputs of a running simulation.
+type Display interface {
+	// Show is called with the current time and outputs after every
+	// step of the simulation.
+	Show(t time.Duration, outputs []sim.Output)
+
+	// Interrupt tells the display that something else has been
+	// written to its output, such as an input prompt.
+	Interrupt()
+}
+
+// New makes a Display which writes to the given file. If the file
+// is a terminal, the outputs are redrawn in place, with one line
+// per output. Otherwise, a line is logged whenever an output changes.
+func New(f *os.File) Display {
+	if isTerminal(f) {
+		return &Terminal{W: f}
+	}
+
+	return &Log{W: f}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// A Terminal display keeps one line per output, and redraws them
+// using ANSI escape codes whenever any of them change.
+type Terminal struct {
+	W io.Writer
+
+	lines []string
+}
+
+// Show redraws the outputs, if any have changed since the last call.
+func (d *Terminal) Show(t time.Duration, outputs []sim.Output) {
+	lines := make([]string, len(outputs))
+	for i, out := range outputs {
+		lines[i] = line(out)
+	}
+
+	if equal(lines, d.lines) {
+		return
+	}
+
+	// move the cursor back up to the first line drawn last time
+	if len(d.lines) > 0 {
+		fmt.Fprintf(d.W, "\x1b[%dA", len(d.lines))
+	}
+
+	for _, line := range lines {
+		fmt.Fprintf(d.W, "\r\x1b[2K%s\n", line)
+	}
+
+	d.lines = lines
+}
+
+// Interrupt makes the next call to Show draw the outputs below
+// whatever has been written since, instead of redrawing them in
+// place.
+func (d *Terminal) Interrupt() {
+	d.lines = nil
+}
+
+// A Log display prints an output, along with the current time,
+// whenever its value changes. It's used when the output isn't a
+// terminal, such as when it's redirected to a file.
+type Log struct {
+	W io.Writer
+
+	// values holds the value each output had last time, by its
+	// index, since several outputs can have the same label.
+	values map[int]string
+}
+
+// Show prints each output which has changed since the last call.
+func (d *Log) Show(t time.Duration, outputs []sim.Output) {
+	if d.values == nil {
+		d.values = make(map[int]string)
+	}
+
+	for i, out := range outputs {
+		if prev, ok := d.values[i]; ok && prev == out.Value {
+			continue
+		}
+
+		fmt.Fprintf(d.W, "[%s] %s\n", t, line(out))
+		d.values[i] = out.Value
+	}
+}
+
+// Interrupt does nothing, since a Log never redraws anything.
+func (d *Log) Interrupt() {}
+
+// line describes an output, including its builtin, so that outputs
+// with the same label can be told apart.
+func line(out sim.Output) string {
+	return fmt.Sprintf("%s %s = %s", out.Builtin, out.Label, out.Value)
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
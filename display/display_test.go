@@ -0,0 +1,38 @@
+package display_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/zac-garby/booleang/display"
+	"github.com/zac-garby/booleang/sim"
+)
+
+// outputs are the outputs of obit (%n) and onumu (%n), which have
+// the same label.
+func outputs(bits, number string) []sim.Output {
+	return []sim.Output{
+		{Builtin: "obit", Label: "%n", Value: bits},
+		{Builtin: "onumu", Label: "%n", Value: number},
+	}
+}
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	d := &Log{W: &buf}
+
+	d.Show(0, outputs("1 0 1", "5"))
+	d.Show(time.Second, outputs("1 0 1", "5"))
+	d.Show(2*time.Second, outputs("0 0 1", "4"))
+
+	expected := `[0s] obit %n = 1 0 1
+[0s] onumu %n = 5
+[2s] obit %n = 0 0 1
+[2s] onumu %n = 4
+`
+
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
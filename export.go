@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/zac-garby/booleang/hdl"
+	"github.com/zac-garby/booleang/interchange"
+	"github.com/zac-garby/booleang/netlist"
+)
+
+// exportCommand converts a circuit, and every circuit it calls, into
+// another language, or into a JSON document holding the program and
+// the circuit's netlist:
+//
+//	bl export [flags] <file> [circuit]
+//
+// The circuit is main if it isn't given.
+func exportCommand(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+
+	format := fs.String("format", "verilog", "the language to export to: verilog, vhdl or json")
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bl export [flags] <file> [circuit]")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	switch *format {
+	case "verilog", "vhdl":
+		modules := elaborateModules(fs.Arg(0), fs.Arg(1))
+
+		var err error
+		if *format == "vhdl" {
+			err = hdl.WriteVHDL(os.Stdout, modules)
+		} else {
+			err = hdl.WriteVerilog(os.Stdout, modules)
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case "json":
+		circuit := netlist.Main
+		if fs.NArg() == 2 {
+			circuit = fs.Arg(1)
+		}
+
+		name, generics, err := parseCircuitName(circuit)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+
+		prog := load(fs.Arg(0), false)
+
+		net, err := netlist.ElaborateCircuit(prog, name, generics...)
+		if err != nil {
+			report(err)
+			os.Exit(1)
+		}
+
+		if err := interchange.Encode(os.Stdout, prog, net); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format '%s'. expected verilog, vhdl or json\n", *format)
+		os.Exit(2)
+	}
+}
+
+// elaborateModules builds a module for the named circuit in a file,
+// and for each circuit it calls, and exits if it can't. The circuit
+// is main if its name is empty.
+func elaborateModules(path, circuit string) []*hdl.Module {
+	if circuit == "" {
+		circuit = netlist.Main
+	}
+
+	name, generics, err := parseCircuitName(circuit)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	prog := load(path, false)
+
+	nets, err := netlist.ElaborateModules(prog, name, generics...)
+	if err != nil {
+		report(err)
+		os.Exit(1)
+	}
+
+	modules, err := hdl.Modules(nets)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	return modules
+}
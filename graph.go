@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/zac-garby/booleang/graph"
+)
+
+// graphCommand draws a circuit as a Graphviz DOT diagram, or as an
+// SVG schematic:
+//
+//	bl graph [flags] <file> [circuit]
+//
+// The circuit is main if it isn't given.
+func graphCommand(args []string) {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+
+	var (
+		format = fs.String("format", "dot", "the format to draw the circuit in: dot or svg")
+		style  = fs.String("style", "ansi", "the style of the gates in a schematic: ansi or iec")
+		expand = fs.Bool("expand", false, "draw the insides of the circuits it calls, instead of boxes")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bl graph [flags] <file> [circuit]")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	opts := graph.Options{Expand: *expand}
+
+	switch *style {
+	case "ansi":
+		opts.Style = graph.ANSI
+	case "iec":
+		opts.Style = graph.IEC
+	default:
+		fmt.Fprintf(os.Stderr, "unknown style '%s'. expected ansi or iec\n", *style)
+		os.Exit(2)
+	}
+
+	write := graph.WriteDOT
+	switch *format {
+	case "dot":
+	case "svg":
+		write = graph.WriteSVG
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format '%s'. expected dot or svg\n", *format)
+		os.Exit(2)
+	}
+
+	modules := elaborateModules(fs.Arg(0), fs.Arg(1))
+
+	if err := write(os.Stdout, modules, opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
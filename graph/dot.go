@@ -0,0 +1,154 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/zac-garby/booleang/hdl"
+	"github.com/zac-garby/booleang/netlist"
+)
+
+// shapes are the shapes of the nodes of each kind of gate.
+var shapes = map[netlist.Op]string{
+	netlist.Not:  "invtriangle",
+	netlist.And:  "box",
+	netlist.Nand: "box",
+	netlist.Or:   "ellipse",
+	netlist.Nor:  "ellipse",
+	netlist.Xor:  "diamond",
+	netlist.Xnor: "diamond",
+	netlist.Imp:  "hexagon",
+}
+
+// WriteDOT draws the last of a list of modules, which should be in
+// the order returned by hdl.Modules. Each gate is a node shaped after
+// its operator, and each register is an edge labelled with its name.
+func WriteDOT(w io.Writer, modules []*hdl.Module, opts Options) error {
+	if len(modules) == 0 {
+		return fmt.Errorf("there aren't any modules to draw")
+	}
+
+	top := modules[len(modules)-1]
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "digraph %s {\n", quote(top.Circuit))
+	b.WriteString("    rankdir=LR;\n")
+	b.WriteString("    node [fontname=\"monospace\"];\n")
+	b.WriteString("    edge [fontname=\"monospace\", fontsize=10];\n")
+
+	dotGraph(&b, build(top, opts), "    ")
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotGraph writes the nodes of a graph, and then its edges, since an
+// edge to a node which hasn't been written yet would put the node in
+// the wrong cluster.
+func dotGraph(b *strings.Builder, g *graph, indent string) {
+	for _, n := range g.nodes {
+		var attrs string
+
+		switch n.kind {
+		case inputNode:
+			attrs = "shape=rarrow"
+			if n.clock {
+				attrs += ", style=dashed"
+			}
+
+		case outputNode:
+			attrs = "shape=rarrow"
+
+		case constNode:
+			attrs = "shape=plaintext"
+
+		case gateNode:
+			attrs = "shape=" + shapes[n.op]
+
+		case registerNode:
+			attrs = "shape=box, style=bold"
+
+		case instanceNode:
+			label := fmt.Sprintf(
+				"{{%s}|%s|{%s}}",
+				recordFields("i", n.in),
+				escapeRecord(n.label),
+				recordFields("o", n.out),
+			)
+
+			fmt.Fprintf(b, "%s%s [shape=record, label=%s];\n", indent, quote(n.id), quote(label))
+			continue
+
+		case clusterNode:
+			fmt.Fprintf(b, "%ssubgraph %s {\n", indent, quote(n.id))
+			fmt.Fprintf(b, "%s    label=%s;\n", indent, quote(n.label))
+			fmt.Fprintf(b, "%s    style=rounded;\n", indent)
+			dotGraph(b, n.graph, indent+"    ")
+			fmt.Fprintf(b, "%s}\n", indent)
+			continue
+		}
+
+		fmt.Fprintf(b, "%s%s [label=%s, %s];\n", indent, quote(n.id), quote(n.label), attrs)
+	}
+
+	for _, e := range g.edges {
+		from := quote(e.from.node.id)
+		if e.from.node.kind == instanceNode {
+			from += fmt.Sprintf(":o_%s:e", e.from.node.out[e.from.pin].port)
+		}
+
+		to := quote(e.to.node.id)
+		if e.to.node.kind == instanceNode {
+			to += fmt.Sprintf(":i_%s:w", e.to.node.in[e.to.pin].port)
+		}
+
+		switch {
+		case e.clock:
+			fmt.Fprintf(b, "%s%s -> %s [style=dashed];\n", indent, from, to)
+		case e.label != "":
+			fmt.Fprintf(b, "%s%s -> %s [label=%s];\n", indent, from, to, quote(e.label))
+		default:
+			fmt.Fprintf(b, "%s%s -> %s;\n", indent, from, to)
+		}
+	}
+}
+
+// recordFields makes a field of a record for each port with a pin in
+// a list.
+func recordFields(prefix string, pins []pin) string {
+	var fields []string
+
+	for i, p := range pins {
+		if i > 0 && pins[i-1].port == p.port {
+			continue
+		}
+
+		fields = append(fields, fmt.Sprintf("<%s_%s> %s", prefix, p.port, p.port))
+	}
+
+	return strings.Join(fields, "|")
+}
+
+func quote(s string) string {
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
+// escapeRecord escapes the characters which have a special meaning
+// in the label of a record node.
+func escapeRecord(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		if strings.ContainsRune(`{}|<> `, r) {
+			b.WriteByte('\\')
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
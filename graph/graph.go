@@ -0,0 +1,454 @@
+// Package graph draws circuits, either as Graphviz DOT diagrams or
+// as SVG schematics.
+package graph
+
+import (
+	"fmt"
+
+	"github.com/zac-garby/booleang/hdl"
+	"github.com/zac-garby/booleang/netlist"
+)
+
+// Options control how a circuit is drawn.
+type Options struct {
+	// Expand draws the insides of each circuit called by the drawn
+	// circuit, instead of a single box.
+	Expand bool
+
+	// Style is the style of the gate symbols in a schematic.
+	Style Style
+}
+
+// A Style is a set of gate symbols.
+type Style int
+
+// The styles of gate symbol.
+const (
+	// ANSI symbols have a distinctive shape for each kind of gate.
+	ANSI Style = iota
+
+	// IEC symbols are rectangles, labelled with the function of the
+	// gate, e.g. ≥1 for OR.
+	IEC
+)
+
+// A graph is the nodes of a circuit, and the edges between them.
+// Each register in the circuit is an edge, or a fan of edges from
+// the same pin.
+type graph struct {
+	nodes []*node
+	edges []*edge
+}
+
+type kind int
+
+const (
+	inputNode kind = iota
+	outputNode
+	constNode
+	gateNode
+	registerNode
+	instanceNode
+	clusterNode
+)
+
+type node struct {
+	id    string
+	kind  kind
+	label string
+
+	// in and out are the pins which edges go into and come out of.
+	in, out []pin
+
+	// op is the operation of a gate, and clock is set for the input
+	// ports which are clocks.
+	op    netlist.Op
+	clock bool
+
+	// inner is set for the ports of an expanded call.
+	inner bool
+
+	// graph is the insides of a cluster.
+	graph *graph
+}
+
+// A pin is a bit of one of a node's ports. Gates have a pin for each
+// argument, which don't have names.
+type pin struct {
+	port  string
+	bit   int
+	label string
+}
+
+// An end is one of the ends of an edge.
+type end struct {
+	node *node
+	pin  int
+}
+
+type edge struct {
+	from, to end
+	label    string
+
+	// clock is set for the edges from a clock to the registers it
+	// updates.
+	clock bool
+}
+
+// build makes a graph of a module.
+func build(mod *hdl.Module, opts Options) *graph {
+	b := &builder{
+		opts: opts,
+	}
+
+	return b.module(mod, "").graph
+}
+
+type builder struct {
+	opts Options
+
+	// count is used to give each constant and gate a unique id.
+	count int
+}
+
+// A scope builds the graph of one module, or an expanded instance of
+// one. The ids of its nodes all start with prefix.
+type scope struct {
+	*builder
+
+	graph  *graph
+	mod    *hdl.Module
+	prefix string
+
+	ports map[string]*node
+	regs  map[string]*node
+
+	// wires maps each wire which is driven by an assignment to its
+	// value, and outputs maps each wire driven by an instance to the
+	// instance's output.
+	wires   map[string]hdl.Expr
+	outputs map[string]end
+
+	// sources holds the end each wire has already been drawn as, and
+	// gates holds the node of each gate.
+	sources map[string]end
+	gates   map[*hdl.Gate]*node
+}
+
+// module adds the ports, gates, instances and registers of a module
+// to a new graph.
+func (b *builder) module(mod *hdl.Module, prefix string) *scope {
+	s := &scope{
+		builder: b,
+		graph:   &graph{},
+		mod:     mod,
+		prefix:  prefix,
+		ports:   make(map[string]*node),
+		regs:    make(map[string]*node),
+		wires:   make(map[string]hdl.Expr),
+		outputs: make(map[string]end),
+		sources: make(map[string]end),
+		gates:   make(map[*hdl.Gate]*node),
+	}
+
+	for _, port := range mod.Inputs {
+		n := s.node(s.id("port", port.Name), inputNode, port.Name)
+		n.out = pins(port)
+		n.clock = port.Kind == hdl.Clock
+		s.ports[port.Name] = n
+	}
+
+	for _, port := range mod.Outputs {
+		n := s.node(s.id("port", port.Name), outputNode, port.Name)
+		n.in = pins(port)
+		s.ports[port.Name] = n
+	}
+
+	// the ports of an expanded call are connected on both sides.
+	if prefix != "" {
+		for _, n := range s.ports {
+			n.inner = true
+
+			if n.kind == inputNode {
+				n.in = n.out
+			} else {
+				n.out = n.in
+			}
+		}
+	}
+
+	for _, reg := range mod.Regs {
+		n := s.node(s.id("reg", reg.Name), registerNode, mod.Registers[reg.Name])
+		n.in = []pin{{port: "d"}, {port: "clk"}}
+		n.out = []pin{{port: "q"}}
+		s.regs[reg.Name] = n
+	}
+
+	for _, stmt := range mod.Body {
+		switch st := stmt.(type) {
+		case *hdl.Assign:
+			if port, ok := s.ports[st.Target.Name]; ok {
+				src, _ := s.source(st.Value)
+				s.edge(src, end{port, bit(st.Target)}, refLabel(st.Target))
+			} else {
+				s.wires[st.Target.Name] = st.Value
+			}
+
+		case *hdl.Instance:
+			s.instance(st)
+		}
+	}
+
+	for _, proc := range mod.Processes {
+		for _, update := range proc.Updates {
+			src, label := s.source(update.Value)
+			if label == "" {
+				label = mod.Registers[update.Reg]
+			}
+
+			reg := s.regs[update.Reg]
+			s.edge(src, end{reg, 0}, label)
+
+			s.graph.edges = append(s.graph.edges, &edge{
+				from:  end{s.ports[proc.Clock.Name], 0},
+				to:    end{reg, 1},
+				clock: true,
+			})
+		}
+	}
+
+	return s
+}
+
+// instance adds an instance of a module, either as a box with a pin
+// for each bit of the module's ports, or as a cluster.
+func (s *scope) instance(inst *hdl.Instance) {
+	var target, source func(port *hdl.Port, bit int) end
+
+	if s.opts.Expand {
+		sub := s.module(inst.Module, s.prefix+inst.Name+".")
+
+		n := s.node("cluster_"+sub.prefix, clusterNode, inst.Name+": "+inst.Module.Circuit)
+		n.graph = sub.graph
+
+		target = func(port *hdl.Port, bit int) end {
+			return end{sub.ports[port.Name], bit}
+		}
+
+		source = target
+	} else {
+		n := s.node(s.id("inst", inst.Name), instanceNode, inst.Module.Circuit)
+
+		for _, port := range inst.Module.Inputs {
+			n.in = append(n.in, pins(port)...)
+		}
+
+		for _, port := range inst.Module.Outputs {
+			n.out = append(n.out, pins(port)...)
+		}
+
+		target = func(port *hdl.Port, bit int) end {
+			return end{n, index(n.in, port.Name, bit)}
+		}
+
+		source = func(port *hdl.Port, bit int) end {
+			return end{n, index(n.out, port.Name, bit)}
+		}
+	}
+
+	for _, conn := range inst.Connections {
+		if contains(inst.Module.Outputs, conn.Port) {
+			for i, value := range conn.Values {
+				s.outputs[value.(hdl.Ref).Name] = source(conn.Port, i)
+			}
+
+			continue
+		}
+
+		for i, value := range conn.Values {
+			src, label := s.source(value)
+			if label == "" && conn.Port.Bus {
+				label = fmt.Sprintf("%s[%d]", conn.Port.Name, i)
+			}
+
+			s.edge(src, target(conn.Port, i), label)
+		}
+	}
+}
+
+// source returns the end which an expression comes out of, adding
+// it to the graph if it isn't there yet, and the label of the edges
+// leading out of it, which is the name of the register it's the
+// value of.
+func (s *scope) source(e hdl.Expr) (end, string) {
+	switch ex := e.(type) {
+	case hdl.Const:
+		s.count++
+
+		label := "0"
+		if ex {
+			label = "1"
+		}
+
+		n := s.node(s.id("const", fmt.Sprint(s.count)), constNode, label)
+		n.out = []pin{{}}
+
+		return end{n, 0}, ""
+
+	case hdl.Ref:
+		label := s.mod.Registers[ex.Name]
+
+		if value, ok := s.wires[ex.Name]; ok {
+			src, ok := s.sources[ex.Name]
+			if !ok {
+				var inner string
+				src, inner = s.source(value)
+				s.sources[ex.Name] = src
+
+				if label == "" {
+					label = inner
+				}
+			}
+
+			return src, label
+		}
+
+		if src, ok := s.outputs[ex.Name]; ok {
+			return src, label
+		}
+
+		if reg, ok := s.regs[ex.Name]; ok {
+			return end{reg, 0}, label
+		}
+
+		return end{s.ports[ex.Name], bit(ex)}, refLabel(ex)
+
+	case *hdl.Gate:
+		if n, ok := s.gates[ex]; ok {
+			return end{n, 0}, ""
+		}
+
+		s.count++
+
+		label := "!"
+		if sym, ok := netlist.Symbols[ex.Op]; ok {
+			label = sym
+		}
+
+		n := s.node(s.id("gate", fmt.Sprint(s.count)), gateNode, label)
+		n.op = ex.Op
+		n.in = make([]pin, len(ex.Args))
+		n.out = []pin{{}}
+		s.gates[ex] = n
+
+		for i, arg := range ex.Args {
+			src, label := s.source(arg)
+			s.edge(src, end{n, i}, label)
+		}
+
+		return end{n, 0}, ""
+	}
+
+	panic(fmt.Sprintf("unknown expression %T", e))
+}
+
+func (s *scope) id(kind, name string) string {
+	return s.prefix + kind + "." + name
+}
+
+func (s *scope) node(id string, kind kind, label string) *node {
+	n := &node{
+		id:    id,
+		kind:  kind,
+		label: label,
+	}
+
+	s.graph.nodes = append(s.graph.nodes, n)
+
+	return n
+}
+
+func (s *scope) edge(from, to end, label string) {
+	s.graph.edges = append(s.graph.edges, &edge{
+		from:  from,
+		to:    to,
+		label: label,
+	})
+}
+
+// pins returns a pin for each bit of a port.
+func pins(port *hdl.Port) []pin {
+	if !port.Bus {
+		return []pin{{port: port.Name, label: port.Name}}
+	}
+
+	ps := make([]pin, port.Width)
+	for i := range ps {
+		ps[i] = pin{
+			port:  port.Name,
+			bit:   i,
+			label: fmt.Sprintf("%s[%d]", port.Name, i),
+		}
+	}
+
+	return ps
+}
+
+// index finds the pin for a bit of a port.
+func index(pins []pin, port string, bit int) int {
+	for i, p := range pins {
+		if p.port == port && p.bit == bit {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// flatten returns all of the nodes and edges of a graph and of the
+// clusters inside it, leaving out the clusters themselves.
+func (g *graph) flatten() ([]*node, []*edge) {
+	var (
+		nodes []*node
+		edges = append([]*edge{}, g.edges...)
+	)
+
+	for _, n := range g.nodes {
+		if n.kind != clusterNode {
+			nodes = append(nodes, n)
+			continue
+		}
+
+		ns, es := n.graph.flatten()
+		nodes = append(nodes, ns...)
+		edges = append(edges, es...)
+	}
+
+	return nodes, edges
+}
+
+func bit(ref hdl.Ref) int {
+	if ref.Index < 0 {
+		return 0
+	}
+
+	return ref.Index
+}
+
+func refLabel(ref hdl.Ref) string {
+	if ref.Index < 0 {
+		return ref.Name
+	}
+
+	return fmt.Sprintf("%s[%d]", ref.Name, ref.Index)
+}
+
+func contains(ports []*hdl.Port, port *hdl.Port) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,183 @@
+package graph_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/zac-garby/booleang/graph"
+	"github.com/zac-garby/booleang/hdl"
+	"github.com/zac-garby/booleang/netlist"
+	"github.com/zac-garby/booleang/parser"
+)
+
+const input = `
+circuit adder (a, b, cin) -> (sum, cout) {
+	((a ^ b) ^ cin) -> sum;
+	(((a ^ b) & cin) | (a & b)) -> cout;
+}
+
+circuit add2 (a[2], b[2]) -> (s[2], carry) {
+	adder (a[0], b[0], 0) -> (s[0], c);
+	adder (a[1], b[1], c) -> (s[1], carry);
+}
+
+circuit blink {
+	1 -> x;
+
+	clock 1s {
+		!x -> x;
+	}
+}
+`
+
+func draw(t *testing.T, circuit string, opts Options) string {
+	return write(t, WriteDOT, circuit, opts)
+}
+
+func drawSVG(t *testing.T, circuit string, opts Options) string {
+	return write(t, WriteSVG, circuit, opts)
+}
+
+func write(t *testing.T, fn func(io.Writer, []*hdl.Module, Options) error, circuit string, opts Options) string {
+	prog, err := parser.New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nets, err := netlist.ElaborateModules(prog, circuit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modules, err := hdl.Modules(nets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := fn(&b, modules, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.String()
+}
+
+func TestGates(t *testing.T) {
+	out := draw(t, "adder", Options{})
+
+	for _, want := range []string{
+		`digraph "adder" {`,
+		`"port.a" [label="a", shape=rarrow];`,
+		`[label="^", shape=diamond];`,
+		`[label="&", shape=box];`,
+		`[label="|", shape=ellipse];`,
+		`-> "port.cout" [label="cout"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the diagram to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestInstances(t *testing.T) {
+	out := draw(t, "add2", Options{})
+
+	for _, want := range []string{
+		`"inst.adder_inst" [shape=record, label="{{<i_a> a|<i_b> b|<i_cin> cin}|adder|{<o_sum> sum|<o_cout> cout}}"];`,
+		`"port.a" -> "inst.adder_inst":i_a:w [label="a[0]"];`,
+		`"inst.adder_inst":o_cout:e -> "inst.adder_inst_2":i_cin:w [label="c"];`,
+		`"inst.adder_inst_2":o_sum:e -> "port.s" [label="s[1]"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the diagram to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	if strings.Contains(out, "cluster") {
+		t.Errorf("didn't expect any clusters, got:\n%s", out)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	out := draw(t, "add2", Options{Expand: true})
+
+	for _, want := range []string{
+		`subgraph "cluster_adder_inst." {`,
+		`label="adder_inst: adder";`,
+		`"adder_inst.port.sum" -> "port.s" [label="s[0]"];`,
+		`"adder_inst.port.cout" -> "adder_inst_2.port.cin" [label="c"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the diagram to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestClocks(t *testing.T) {
+	out := draw(t, "blink", Options{})
+
+	for _, want := range []string{
+		`"reg.x" [label="x", shape=box, style=bold];`,
+		`"port.clk_0" -> "reg.x" [style=dashed];`,
+		`[label="!", shape=invtriangle];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the diagram to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	for _, circuit := range []string{"adder", "add2", "blink"} {
+		for _, opts := range []Options{{}, {Style: IEC}, {Expand: true}} {
+			out := drawSVG(t, circuit, opts)
+
+			dec := xml.NewDecoder(strings.NewReader(out))
+			for {
+				_, err := dec.Token()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Errorf("%s: invalid SVG: %s", circuit, err)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	ansi := drawSVG(t, "adder", Options{})
+
+	for _, want := range []string{
+		// the body of an AND gate.
+		`<path d="M10 0 H29 A19 20 0 0 1 29 40 H10 Z" fill="#fff"/>`,
+
+		// the extra curve behind an XOR gate.
+		`<path d="M5 0 Q13 20 5 40"/>`,
+	} {
+		if !strings.Contains(ansi, want) {
+			t.Errorf("expected the schematic to contain %q, got:\n%s", want, ansi)
+		}
+	}
+
+	iec := drawSVG(t, "adder", Options{Style: IEC})
+
+	for _, want := range []string{">&amp;<", ">≥1<", ">=1<"} {
+		if !strings.Contains(iec, want) {
+			t.Errorf("expected the schematic to contain %q, got:\n%s", want, iec)
+		}
+	}
+
+	blink := drawSVG(t, "blink", Options{})
+
+	// the not gate's bubble, and the dashed wire from the clock.
+	for _, want := range []string{`<circle cx="52" cy="20" r="4" fill="#fff"/>`, `stroke-dasharray="4 2"`} {
+		if !strings.Contains(blink, want) {
+			t.Errorf("expected the schematic to contain %q, got:\n%s", want, blink)
+		}
+	}
+}
@@ -0,0 +1,768 @@
+package graph
+
+import (
+	"math"
+	"sort"
+)
+
+// The layout puts the nodes of a graph into layers from left to
+// right, so that each edge which isn't part of a loop goes from one
+// layer to a later one. An edge which skips over layers is passed
+// through a dummy in each one, so every wire can be routed in the
+// channel between two neighbouring layers. Each group of wires from
+// the same pin gets its own vertical track in a channel.
+//
+// The edges which go backwards, which come from loops through the
+// registers of a clock, are routed below everything else instead.
+
+const (
+	margin    = 20
+	nodeGap   = 24
+	dummyGap  = 12
+	trackGap  = 8
+	laneGap   = 10
+	charWidth = 7
+)
+
+// A box is where a node, or a dummy, is put.
+type box struct {
+	node *node
+
+	layer, order int
+	x, y, w, h   float64
+
+	// in and out are the heights of the pins of the node, relative to
+	// the top of the box.
+	in, out []float64
+
+	// pos is used to sort the boxes in a layer.
+	pos float64
+}
+
+func (b *box) inPin(i int) point {
+	return point{b.x, b.y + b.in[i]}
+}
+
+func (b *box) outPin(i int) point {
+	return point{b.x + b.w, b.y + b.out[i]}
+}
+
+type point struct {
+	x, y float64
+}
+
+// A hop is part of an edge from one layer to the next.
+type hop struct {
+	from, to       *box
+	fromPin, toPin int
+	label          string
+	clock          bool
+}
+
+// A loop is an edge which doesn't go forwards.
+type loop struct {
+	from, to       *box
+	fromPin, toPin int
+	label          string
+	clock          bool
+	lane           float64
+}
+
+// A net is a group of wires in a channel which share a track.
+type net struct {
+	key   netKey
+	track float64
+
+	// ys counts the horizontal wires joining the track at each
+	// height, and targets holds the heights of the ones leading to
+	// the right.
+	ys      map[float64]int
+	targets map[float64]bool
+
+	label string
+	start point
+}
+
+// A netKey identifies a net: either the pin its wires come out of,
+// or a loop, for the part of the loop going back into its target.
+type netKey struct {
+	box  *box
+	pin  int
+	loop *loop
+}
+
+type layout struct {
+	boxes  []*box
+	layers [][]*box
+	hops   []*hop
+	loops  []*loop
+
+	// channels holds the nets in the channel to the left of each
+	// layer, and to the right of the last one.
+	channels [][]*net
+	nets     map[int]map[netKey]*net
+
+	width, height float64
+}
+
+// place lays out the nodes and edges of a graph, using a function
+// which gives the size of each node and the heights of its pins.
+func place(nodes []*node, edges []*edge, size func(n *node) (w, h float64, in, out []float64)) *layout {
+	l := &layout{
+		nets: make(map[int]map[netKey]*net),
+	}
+
+	boxes := make(map[*node]*box)
+
+	for _, n := range nodes {
+		b := &box{node: n}
+		b.w, b.h, b.in, b.out = size(n)
+		boxes[n] = b
+		l.boxes = append(l.boxes, b)
+	}
+
+	l.layer(edges, boxes)
+
+	layers := 0
+	for _, b := range l.boxes {
+		if b.layer+1 > layers {
+			layers = b.layer + 1
+		}
+	}
+
+	l.layers = make([][]*box, layers)
+	for _, b := range l.boxes {
+		l.layers[b.layer] = append(l.layers[b.layer], b)
+	}
+
+	// dummies are shared by the edges from the same pin, so they're
+	// found by the pin and the layer.
+	type dummyKey struct {
+		from  *box
+		pin   int
+		layer int
+	}
+
+	dummies := make(map[dummyKey]*box)
+	seen := make(map[hop]bool)
+
+	addHop := func(h hop) {
+		key := h
+		key.label = ""
+
+		if !seen[key] {
+			seen[key] = true
+			l.hops = append(l.hops, &h)
+		}
+	}
+
+	for _, e := range edges {
+		from, to := boxes[e.from.node], boxes[e.to.node]
+
+		if to.layer <= from.layer {
+			l.loops = append(l.loops, &loop{
+				from:    from,
+				to:      to,
+				fromPin: e.from.pin,
+				toPin:   e.to.pin,
+				label:   e.label,
+				clock:   e.clock,
+			})
+
+			continue
+		}
+
+		prev, prevPin, label := from, e.from.pin, e.label
+
+		for layer := from.layer + 1; layer < to.layer; layer++ {
+			key := dummyKey{from, e.from.pin, layer}
+
+			dummy, ok := dummies[key]
+			if !ok {
+				dummy = &box{layer: layer, in: []float64{0}, out: []float64{0}}
+				dummies[key] = dummy
+				l.boxes = append(l.boxes, dummy)
+				l.layers[layer] = append(l.layers[layer], dummy)
+			}
+
+			addHop(hop{from: prev, fromPin: prevPin, to: dummy, label: label, clock: e.clock})
+			prev, prevPin, label = dummy, 0, ""
+		}
+
+		addHop(hop{from: prev, fromPin: prevPin, to: to, toPin: e.to.pin, label: label, clock: e.clock})
+	}
+
+	l.order()
+	l.position()
+	l.route()
+
+	return l
+}
+
+// layer puts each box in a layer, so that the edges which aren't part
+// of a loop all go forwards. Each node is put as far left as it can
+// be, except for constants, which are put just before the first node
+// which uses them, and outputs, which are all put in the last layer, apart from the outputs
+// of expanded calls.
+func (l *layout) layer(edges []*edge, boxes map[*node]*box) {
+	succs := make(map[*box][]*box)
+	for _, e := range edges {
+		from, to := boxes[e.from.node], boxes[e.to.node]
+		succs[from] = append(succs[from], to)
+	}
+
+	// a depth-first search finds the edges which close loops, which
+	// are the ones leading back to a box which is still being
+	// searched.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var (
+		state = make(map[*box]int)
+		back  = make(map[[2]*box]bool)
+		visit func(b *box)
+	)
+
+	visit = func(b *box) {
+		state[b] = visiting
+
+		for _, s := range succs[b] {
+			switch state[s] {
+			case unvisited:
+				visit(s)
+			case visiting:
+				back[[2]*box{b, s}] = true
+			}
+		}
+
+		state[b] = visited
+	}
+
+	for _, b := range l.boxes {
+		if state[b] == unvisited {
+			visit(b)
+		}
+	}
+
+	// the boxes are then layered in topological order, ignoring the
+	// edges which close loops.
+	var (
+		preds   = make(map[*box]int)
+		forward = make(map[*box][]*box)
+	)
+
+	for _, b := range l.boxes {
+		for _, s := range succs[b] {
+			if !back[[2]*box{b, s}] {
+				forward[b] = append(forward[b], s)
+				preds[s]++
+			}
+		}
+	}
+
+	var queue []*box
+	for _, b := range l.boxes {
+		if preds[b] == 0 {
+			queue = append(queue, b)
+		}
+	}
+
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+
+		for _, s := range forward[b] {
+			if b.layer+1 > s.layer {
+				s.layer = b.layer + 1
+			}
+
+			if preds[s]--; preds[s] == 0 {
+				queue = append(queue, s)
+			}
+		}
+	}
+
+	last := 0
+	for _, b := range l.boxes {
+		if b.node.kind == constNode {
+			first := -1
+			for _, s := range forward[b] {
+				if first < 0 || s.layer < first {
+					first = s.layer
+				}
+			}
+
+			if first > 0 {
+				b.layer = first - 1
+			}
+		}
+
+		if (b.node.kind != outputNode || b.node.inner) && b.layer+1 > last {
+			last = b.layer + 1
+		}
+	}
+
+	for _, b := range l.boxes {
+		if b.node.kind == outputNode && !b.node.inner {
+			b.layer = last
+		}
+	}
+}
+
+// order sorts the boxes in each layer by the average position of the
+// boxes they're connected to, sweeping forwards and backwards a few
+// times, to reduce the number of crossing wires.
+func (l *layout) order() {
+	l.number()
+
+	for i := 0; i < 4; i++ {
+		l.sweep(func(h *hop) (*box, *box, float64) {
+			return h.to, h.from, fraction(h.from.out, h.fromPin)
+		})
+
+		l.sweep(func(h *hop) (*box, *box, float64) {
+			return h.from, h.to, fraction(h.to.in, h.toPin)
+		})
+	}
+}
+
+// sweep sorts the boxes of every layer by the positions of the boxes
+// they're connected to by each hop, which are found by a function
+// returning the box being sorted, the box it's connected to, and a
+// fraction to add to the connected box's position so that its pins
+// are in order.
+func (l *layout) sweep(ends func(h *hop) (*box, *box, float64)) {
+	var (
+		sum   = make(map[*box]float64)
+		count = make(map[*box]float64)
+	)
+
+	for _, h := range l.hops {
+		b, other, frac := ends(h)
+		sum[b] += float64(other.order) + frac
+		count[b]++
+	}
+
+	for _, layer := range l.layers {
+		for _, b := range layer {
+			if count[b] > 0 {
+				b.pos = sum[b] / count[b]
+			} else {
+				b.pos = float64(b.order)
+			}
+		}
+
+		sort.SliceStable(layer, func(i, j int) bool {
+			return layer[i].pos < layer[j].pos
+		})
+	}
+
+	l.number()
+}
+
+func (l *layout) number() {
+	for _, layer := range l.layers {
+		for i, b := range layer {
+			b.order = i
+		}
+	}
+}
+
+func fraction(pins []float64, pin int) float64 {
+	if len(pins) == 0 {
+		return 0
+	}
+
+	return float64(pin) / float64(len(pins))
+}
+
+// position places the boxes vertically, first stacking them, and then
+// moving each one towards the pins it's connected to, so that as many
+// wires as possible are straight. The last sweep is forwards, so the
+// wires into each box are straightened last.
+func (l *layout) position() {
+	for _, layer := range l.layers {
+		y := 0.0
+		for _, b := range layer {
+			b.y = y
+			y += b.h + gap(b)
+		}
+	}
+
+	var (
+		ins  = make(map[*box][]*hop)
+		outs = make(map[*box][]*hop)
+	)
+
+	for _, h := range l.hops {
+		ins[h.to] = append(ins[h.to], h)
+		outs[h.from] = append(outs[h.from], h)
+	}
+
+	forwards := func(b *box) []float64 {
+		var ys []float64
+		for _, h := range ins[b] {
+			ys = append(ys, h.from.y+h.from.out[h.fromPin]-h.to.in[h.toPin])
+		}
+
+		return ys
+	}
+
+	backwards := func(b *box) []float64 {
+		var ys []float64
+		for _, h := range outs[b] {
+			ys = append(ys, h.to.y+h.to.in[h.toPin]-h.from.out[h.fromPin])
+		}
+
+		return ys
+	}
+
+	for i := 0; i < 3; i++ {
+		for _, layer := range l.layers {
+			align(layer, forwards)
+		}
+
+		for j := len(l.layers) - 1; j >= 0; j-- {
+			align(l.layers[j], backwards)
+		}
+	}
+
+	for _, layer := range l.layers {
+		align(layer, forwards)
+	}
+
+	top := 0.0
+	for _, b := range l.boxes {
+		if b.y < top {
+			top = b.y
+		}
+	}
+
+	for _, b := range l.boxes {
+		b.y = math.Round(b.y + margin - top)
+	}
+}
+
+// align moves the boxes in a layer as close as they can get to the
+// average of the heights they want to be at, without overlapping or
+// changing their order. A box which doesn't want to be anywhere stays
+// where it is.
+//
+// Taking away the space above each box if they were stacked, the
+// problem is to find the closest heights which never decrease, which
+// is solved by pooling neighbours which are out of order.
+func align(layer []*box, want func(b *box) []float64) {
+	type pool struct {
+		sum   float64
+		count int
+	}
+
+	var (
+		pools  []pool
+		offset = make([]float64, len(layer))
+	)
+
+	for i, b := range layer {
+		if i > 0 {
+			above := layer[i-1]
+			offset[i] = offset[i-1] + above.h + gap(above)
+		}
+
+		y := b.y
+		if ys := want(b); len(ys) > 0 {
+			y = 0
+			for _, w := range ys {
+				y += w
+			}
+
+			y /= float64(len(ys))
+		}
+
+		pools = append(pools, pool{y - offset[i], 1})
+
+		for n := len(pools); n > 1; n = len(pools) {
+			a, b := pools[n-2], pools[n-1]
+			if a.sum/float64(a.count) <= b.sum/float64(b.count) {
+				break
+			}
+
+			pools = append(pools[:n-2], pool{a.sum + b.sum, a.count + b.count})
+		}
+	}
+
+	i := 0
+	for _, p := range pools {
+		for j := 0; j < p.count; j++ {
+			layer[i].y = p.sum/float64(p.count) + offset[i]
+			i++
+		}
+	}
+}
+
+func gap(b *box) float64 {
+	if b.node == nil {
+		return dummyGap
+	}
+
+	return nodeGap
+}
+
+// route gives each net a track in its channel, and places the layers
+// and channels from left to right.
+func (l *layout) route() {
+	bottom := 0.0
+	for _, b := range l.boxes {
+		if b.y+b.h > bottom {
+			bottom = b.y + b.h
+		}
+	}
+
+	l.channels = make([][]*net, len(l.layers)+1)
+
+	for i, lp := range l.loops {
+		lp.lane = bottom + float64(i+1)*laneGap
+	}
+
+	// the heights are known, so the nets can be found before the
+	// tracks are given x positions.
+	for _, h := range l.hops {
+		n := l.net(h.to.layer, netKey{box: h.from, pin: h.fromPin}, h.label, h.from.y+h.from.out[h.fromPin])
+		n.join(h.to.y + h.to.in[h.toPin])
+	}
+
+	for _, lp := range l.loops {
+		out := l.net(lp.from.layer+1, netKey{box: lp.from, pin: lp.fromPin}, lp.label, lp.from.y+lp.from.out[lp.fromPin])
+		out.ys[lp.lane]++
+
+		in := l.net(lp.to.layer, netKey{loop: lp}, "", lp.lane)
+		in.join(lp.to.y + lp.to.in[lp.toPin])
+	}
+
+	x := float64(margin)
+
+	for c := 0; c <= len(l.layers); c++ {
+		nets := l.channels[c]
+
+		nets = arrange(nets)
+		l.channels[c] = nets
+
+		if len(nets) > 0 {
+			pad := float64(trackGap)
+			for _, n := range nets {
+				if w := textWidth(n.label, charWidth) + 6; n.label != "" && w > pad {
+					pad = w
+				}
+			}
+
+			for i, n := range nets {
+				n.track = x + pad + float64(i)*trackGap
+			}
+
+			x += pad + float64(len(nets))*trackGap + trackGap
+		}
+
+		if c == len(l.layers) {
+			break
+		}
+
+		width := 0.0
+		for _, b := range l.layers[c] {
+			if b.w > width {
+				width = b.w
+			}
+		}
+
+		if c > 0 && len(nets) == 0 {
+			x += nodeGap
+		}
+
+		for _, b := range l.layers[c] {
+			b.x = x + math.Round((width-b.w)/2)
+		}
+
+		x += width
+	}
+
+	l.width = x + margin
+	l.height = bottom + float64(len(l.loops))*laneGap + margin
+
+	for _, layer := range l.channels {
+		for _, n := range layer {
+			if n.key.box != nil {
+				n.start = n.key.box.outPin(n.key.pin)
+			}
+		}
+	}
+}
+
+// net finds or makes a net in a channel, which has a horizontal wire
+// joining it at a given height.
+func (l *layout) net(channel int, key netKey, label string, y float64) *net {
+	if l.nets[channel] == nil {
+		l.nets[channel] = make(map[netKey]*net)
+	}
+
+	n, ok := l.nets[channel][key]
+	if !ok {
+		n = &net{
+			key:     key,
+			ys:      map[float64]int{y: 1},
+			targets: make(map[float64]bool),
+		}
+
+		l.nets[channel][key] = n
+		l.channels[channel] = append(l.channels[channel], n)
+	}
+
+	if n.label == "" {
+		n.label = label
+	}
+
+	return n
+}
+
+// join adds a wire leading right from the net's track.
+func (n *net) join(y float64) {
+	n.ys[y]++
+	n.targets[y] = true
+}
+
+// arrange puts the nets in a channel in order from left to right,
+// mostly from top to bottom. But the wire leading into a net from the
+// left mustn't overlap a wire leading out of another net to the right
+// at the same height, so the first net's track has to come first.
+// If two nets swap heights, they can't both come first, and one of
+// them overlaps the other.
+func arrange(nets []*net) []*net {
+	sort.SliceStable(nets, func(i, j int) bool {
+		ilo, _ := nets[i].span()
+		jlo, _ := nets[j].span()
+		return ilo < jlo
+	})
+
+	// before reports whether a net's track has to come before
+	// another's.
+	before := func(a, b *net) bool {
+		return a.key.box != nil && b.targets[a.key.box.y+a.key.box.out[a.key.pin]]
+	}
+
+	var (
+		sorted = make([]*net, 0, len(nets))
+		placed = make(map[*net]bool)
+	)
+
+	for len(sorted) < len(nets) {
+		next := -1
+
+		for i, n := range nets {
+			if placed[n] {
+				continue
+			}
+
+			if next < 0 {
+				next = i
+			}
+
+			free := true
+			for _, m := range nets {
+				if m != n && !placed[m] && before(m, n) {
+					free = false
+					break
+				}
+			}
+
+			if free {
+				next = i
+				break
+			}
+		}
+
+		placed[nets[next]] = true
+		sorted = append(sorted, nets[next])
+	}
+
+	return sorted
+}
+
+// span returns the top and bottom of a net's track.
+func (n *net) span() (float64, float64) {
+	first := true
+	var lo, hi float64
+
+	for y := range n.ys {
+		if first || y < lo {
+			lo = y
+		}
+
+		if first || y > hi {
+			hi = y
+		}
+
+		first = false
+	}
+
+	return lo, hi
+}
+
+// junctions returns the heights where three or more wires meet on a
+// net's track.
+func (n *net) junctions() []float64 {
+	lo, hi := n.span()
+
+	var ys []float64
+	for y, count := range n.ys {
+		if y > lo {
+			count++
+		}
+
+		if y < hi {
+			count++
+		}
+
+		if count >= 3 {
+			ys = append(ys, y)
+		}
+	}
+
+	sort.Float64s(ys)
+
+	return ys
+}
+
+// wire returns the points of the wire for a hop.
+func (l *layout) wire(h *hop) []point {
+	var (
+		from  = h.from.outPin(h.fromPin)
+		to    = h.to.inPin(h.toPin)
+		track = l.nets[h.to.layer][netKey{box: h.from, pin: h.fromPin}].track
+	)
+
+	return []point{from, {track, from.y}, {track, to.y}, to}
+}
+
+// loopWire returns the points of the wire for a loop, which goes
+// down to its lane, back to the channel before its target, and up.
+func (l *layout) loopWire(lp *loop) []point {
+	var (
+		from = lp.from.outPin(lp.fromPin)
+		to   = lp.to.inPin(lp.toPin)
+		out  = l.nets[lp.from.layer+1][netKey{box: lp.from, pin: lp.fromPin}].track
+		in   = l.nets[lp.to.layer][netKey{loop: lp}].track
+	)
+
+	return []point{
+		from,
+		{out, from.y},
+		{out, lp.lane},
+		{in, lp.lane},
+		{in, to.y},
+		to,
+	}
+}
+
+func textWidth(s string, char float64) float64 {
+	return float64(len([]rune(s))) * char
+}
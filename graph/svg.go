@@ -0,0 +1,345 @@
+package graph
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/zac-garby/booleang/hdl"
+	"github.com/zac-garby/booleang/netlist"
+)
+
+// The dimensions of the parts of a schematic, in pixels.
+const (
+	gateWidth  = 60
+	gateHeight = 40
+	pinGap     = 16
+	titleRow   = 20
+	bubble     = 4
+	smallChar  = 6
+)
+
+// inverted maps the operations whose output is inverted to the
+// operations they're the inverse of.
+var inverted = map[netlist.Op]netlist.Op{
+	netlist.Nand: netlist.And,
+	netlist.Nor:  netlist.Or,
+	netlist.Xnor: netlist.Xor,
+}
+
+// iecLabels label the rectangles of IEC gate symbols.
+var iecLabels = map[netlist.Op]string{
+	netlist.And: "&",
+	netlist.Or:  "≥1",
+	netlist.Xor: "=1",
+	netlist.Not: "1",
+	netlist.Imp: "≥1",
+}
+
+// WriteSVG draws the last of a list of modules as a schematic, which
+// should be in the order returned by hdl.Modules. The gates are
+// drawn with the symbols of the given style, and the wires between
+// them only run horizontally and vertically.
+func WriteSVG(w io.Writer, modules []*hdl.Module, opts Options) error {
+	if len(modules) == 0 {
+		return fmt.Errorf("there aren't any modules to draw")
+	}
+
+	nodes, edges := build(modules[len(modules)-1], opts).flatten()
+	l := place(nodes, edges, size)
+
+	var b strings.Builder
+
+	fmt.Fprintf(
+		&b,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" font-family="monospace" font-size="12">`+"\n",
+		l.width, l.height,
+	)
+
+	fmt.Fprintf(&b, `<rect width="%g" height="%g" fill="white"/>`+"\n", l.width, l.height)
+	b.WriteString(`<g fill="none" stroke="black" stroke-width="1.5">` + "\n")
+
+	for _, h := range l.hops {
+		polyline(&b, l.wire(h), h.clock)
+	}
+
+	for _, lp := range l.loops {
+		polyline(&b, l.loopWire(lp), lp.clock)
+	}
+
+	for _, box := range l.boxes {
+		if box.node != nil {
+			symbol(&b, box, opts.Style)
+		}
+	}
+
+	b.WriteString("</g>\n")
+
+	for _, channel := range l.channels {
+		for _, n := range channel {
+			for _, y := range n.junctions() {
+				fmt.Fprintf(&b, `<circle cx="%g" cy="%g" r="3" fill="black"/>`+"\n", n.track, y)
+			}
+
+			if n.label != "" && n.key.box.node != nil && n.label != n.key.box.node.label {
+				text(&b, n.start.x+3, n.start.y-4, "start", 10, n.label)
+			}
+		}
+	}
+
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// size returns the size of the symbol of a node, and the heights of
+// its input and output pins.
+func size(n *node) (w, h float64, in, out []float64) {
+	// pins spaces a number of pins out, starting at a height.
+	pins := func(count int, top float64) []float64 {
+		ys := make([]float64, count)
+		for i := range ys {
+			ys[i] = top + float64(i)*pinGap
+		}
+
+		return ys
+	}
+
+	switch n.kind {
+	case inputNode, outputNode:
+		bits := len(n.in)
+		if n.kind == inputNode {
+			bits = len(n.out)
+		}
+
+		w, h = textWidth(portLabel(n), charWidth)+20, float64(bits*pinGap+4)
+
+		if n.inner {
+			return 8, h, pins(bits, pinGap/2+2), pins(bits, pinGap/2+2)
+		}
+
+		if n.kind == inputNode {
+			return w, h, nil, pins(bits, pinGap/2+2)
+		}
+
+		return w, h, pins(bits, pinGap/2+2), nil
+
+	case constNode:
+		return 16, 20, nil, []float64{10}
+
+	case gateNode:
+		if len(n.in) == 1 {
+			return gateWidth, gateHeight, []float64{gateHeight / 2}, []float64{gateHeight / 2}
+		}
+
+		return gateWidth, gateHeight, []float64{gateHeight / 4, gateHeight * 3 / 4}, []float64{gateHeight / 2}
+
+	case registerNode:
+		return 50, 40, []float64{10, 30}, []float64{20}
+
+	case instanceNode:
+		rows := len(n.in)
+		if len(n.out) > rows {
+			rows = len(n.out)
+		}
+
+		var left, right float64
+		for _, p := range n.in {
+			if w := textWidth(p.label, smallChar); w > left {
+				left = w
+			}
+		}
+
+		for _, p := range n.out {
+			if w := textWidth(p.label, smallChar); w > right {
+				right = w
+			}
+		}
+
+		w = left + right + 24
+		if title := textWidth(n.label, charWidth) + 20; title > w {
+			w = title
+		}
+
+		top := float64(titleRow + pinGap/2 + 2)
+
+		return w, float64(titleRow + rows*pinGap + 4), pins(len(n.in), top), pins(len(n.out), top)
+	}
+
+	return 0, 0, nil, nil
+}
+
+// symbol draws the symbol of a node.
+func symbol(b *strings.Builder, box *box, style Style) {
+	n := box.node
+
+	fmt.Fprintf(b, `<g transform="translate(%g %g)">`+"\n", box.x, box.y)
+	defer b.WriteString("</g>\n")
+
+	w, h := box.w, box.h
+
+	switch n.kind {
+	case inputNode, outputNode:
+		if n.inner {
+			// the ports of an expanded call are just points the wires
+			// pass through, labelled with the name of the call.
+			text(b, w/2, -6, "middle", 10, portLabel(n))
+
+			for _, y := range box.in {
+				fmt.Fprintf(b, `<circle cx="%g" cy="%g" r="3" fill="white"/>`+"\n", w/2, y)
+				fmt.Fprintf(b, `<path d="M0 %g H%g"/>`+"\n", y, w)
+			}
+
+			return
+		}
+
+		dash := ""
+		if n.clock {
+			dash = ` stroke-dasharray="4 2"`
+		}
+
+		fmt.Fprintf(b, `<path d="M0 0 H%g L%g %g L%g %g H0 Z" fill="#eef"%s/>`+"\n", w-10, w, h/2, w-10, h, dash)
+		text(b, (w-10)/2, h/2, "middle", 12, n.label)
+
+	case constNode:
+		text(b, w/2, h/2, "middle", 12, n.label)
+
+	case gateNode:
+		gate(b, n.op, len(box.in), style)
+
+	case registerNode:
+		fmt.Fprintf(b, `<rect width="%g" height="%g" fill="#ffe"/>`+"\n", w, h)
+		fmt.Fprintf(b, `<path d="M0 %g L8 %g L0 %g"/>`+"\n", box.in[1]-5, box.in[1], box.in[1]+5)
+		text(b, 4, box.in[0], "start", 10, "D")
+		text(b, w-4, box.out[0], "end", 10, "Q")
+		text(b, w/2, -6, "middle", 12, n.label)
+
+	case instanceNode:
+		fmt.Fprintf(b, `<rect width="%g" height="%g" fill="#efe"/>`+"\n", w, h)
+		fmt.Fprintf(b, `<path d="M0 %d H%g"/>`+"\n", titleRow, w)
+		text(b, w/2, titleRow/2, "middle", 12, n.label)
+
+		for i, p := range n.in {
+			text(b, 4, box.in[i], "start", 10, p.label)
+		}
+
+		for i, p := range n.out {
+			text(b, w-4, box.out[i], "end", 10, p.label)
+		}
+	}
+}
+
+// gate draws a gate symbol, whose body goes from x = 10 to 48, with
+// a stub leading to each of its pins.
+func gate(b *strings.Builder, op netlist.Op, inputs int, style Style) {
+	const (
+		left  = 10
+		right = 48
+		mid   = gateHeight / 2
+	)
+
+	base, invert := op, false
+	if o, ok := inverted[op]; ok {
+		base, invert = o, true
+	}
+
+	ys := []float64{mid}
+	if inputs == 2 {
+		ys = []float64{gateHeight / 4, gateHeight * 3 / 4}
+	}
+
+	// back is where the input stub at a height meets the body.
+	back := func(y float64) float64 {
+		return left
+	}
+
+	if style == IEC {
+		fmt.Fprintf(b, `<rect x="%d" width="%d" height="%d" fill="#fff"/>`+"\n", left, right-left, gateHeight)
+		text(b, (left+right)/2, 12, "middle", 12, iecLabels[base])
+	} else {
+		switch base {
+		case netlist.And:
+			fmt.Fprintf(b, `<path d="M%d 0 H29 A19 20 0 0 1 29 40 H%d Z" fill="#fff"/>`+"\n", left, left)
+
+		case netlist.Not:
+			fmt.Fprintf(b, `<path d="M%d 6 L%d %d L%d 34 Z" fill="#fff"/>`+"\n", left, right, mid, left)
+
+		case netlist.Or, netlist.Xor, netlist.Imp:
+			fmt.Fprintf(b, `<path d="M%d 0 Q36 0 %d %d Q36 40 %d 40 Q18 20 %d 0 Z" fill="#fff"/>`+"\n", left, right, mid, left, left)
+
+			// the back of an OR gate is a quadratic curve, which is 8
+			// pixels deeper in the middle than at the ends.
+			curve := func(x0 float64) func(y float64) float64 {
+				return func(y float64) float64 {
+					t := y / gateHeight
+					return x0 + 2*t*(1-t)*8
+				}
+			}
+
+			back = curve(left)
+
+			if base == netlist.Xor {
+				fmt.Fprintf(b, `<path d="M%d 0 Q%d 20 %d 40"/>`+"\n", left-5, left+3, left-5)
+				back = curve(left - 5)
+			}
+		}
+	}
+
+	for i, y := range ys {
+		end := back(y)
+
+		// a -> b is drawn as !a | b.
+		if op == netlist.Imp && i == 0 {
+			fmt.Fprintf(b, `<circle cx="%g" cy="%g" r="%d" fill="#fff"/>`+"\n", end-bubble, y, bubble)
+			end -= 2 * bubble
+		}
+
+		fmt.Fprintf(b, `<path d="M0 %g H%g"/>`+"\n", y, end)
+	}
+
+	out := float64(right)
+	if invert || op == netlist.Not {
+		fmt.Fprintf(b, `<circle cx="%d" cy="%d" r="%d" fill="#fff"/>`+"\n", right+bubble, mid, bubble)
+		out += 2 * bubble
+	}
+
+	fmt.Fprintf(b, `<path d="M%g %d H%d"/>`+"\n", out, mid, gateWidth)
+}
+
+func polyline(b *strings.Builder, points []point, dashed bool) {
+	var coords []string
+	for i, p := range points {
+		if i > 0 && p == points[i-1] {
+			continue
+		}
+
+		coords = append(coords, fmt.Sprintf("%g,%g", p.x, p.y))
+	}
+
+	dash := ""
+	if dashed {
+		dash = ` stroke-dasharray="4 2"`
+	}
+
+	fmt.Fprintf(b, `<polyline points="%s"%s/>`+"\n", strings.Join(coords, " "), dash)
+}
+
+func text(b *strings.Builder, x, y float64, anchor string, size int, s string) {
+	fmt.Fprintf(
+		b,
+		`<text x="%g" y="%g" text-anchor="%s" dominant-baseline="middle" font-size="%d" fill="black" stroke="none">%s</text>`+"\n",
+		x, y, anchor, size, html.EscapeString(s),
+	)
+}
+
+// portLabel is the label of a port, which includes the name of the
+// call for the ports of an expanded call.
+func portLabel(n *node) string {
+	if n.inner {
+		return n.id[:strings.LastIndex(n.id, ".port.")] + "." + n.label
+	}
+
+	return n.label
+}
@@ -0,0 +1,460 @@
+package hdl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/zac-garby/booleang/hdl"
+	"github.com/zac-garby/booleang/netlist"
+	"github.com/zac-garby/booleang/parser"
+	"github.com/zac-garby/booleang/sim"
+	"github.com/zac-garby/booleang/truth"
+)
+
+const input = `
+circuit adder (a, b, cin) -> (sum, cout) {
+	((a ^ b) ^ cin) -> sum;
+	(((a ^ b) & cin) | (a & b)) -> cout;
+}
+
+circuit addN<N> (a[N], b[N]) -> (s[N], carry) {
+	0 -> c0;
+
+	for i in 0..N {
+		adder (a[i], b[i], c<i>) -> (s[i], c<i+1>);
+	}
+
+	(c<N>) -> carry;
+}
+
+circuit keep (a) -> (o) {
+	(o | a) -> o;
+}
+
+circuit sequence (x, y) -> (r, t) {
+	(x !& y) -> t;
+	keep (x) -> (r);
+	(r ^ t) -> t;
+	keep (y) -> (r);
+	!t -> t;
+}
+
+circuit blink {
+	1 -> x;
+
+	clock 1s {
+		!x -> x;
+	}
+}
+
+circuit toggle () -> (o) {
+	!s -> s;
+	(s) -> o;
+}
+
+circuit once (a) -> (o, p) {
+	toggle () -> (o);
+	(a & o) -> p;
+}
+
+circuit ticking {
+	clock 1s {
+		toggle () -> (o);
+	}
+}
+
+circuit fast {
+	clock 1ns {
+		!x -> x;
+	}
+
+	clock 3ns {
+		!y -> y;
+	}
+}
+
+circuit shift {
+	clock 1s {
+		!x -> x;
+	}
+
+	clock 2s {
+		(x) -> y;
+	}
+}
+
+circuit clash {
+	clock 1s {
+		!x -> x;
+	}
+
+	clock 2s {
+		0 -> x;
+	}
+}
+`
+
+func modules(t *testing.T, circuit string, generics ...int) ([]*Module, *truth.Table) {
+	prog, err := parser.New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nets, err := netlist.ElaborateModules(prog, circuit, generics...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mods, err := Modules(nets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	net, err := netlist.ElaborateCircuit(prog, circuit, generics...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(net.Clocks) > 0 {
+		return mods, nil
+	}
+
+	table, err := truth.Generate(net, truth.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mods, table
+}
+
+// TestModules checks that each module behaves the same as the
+// flattened netlist of its circuit, by interpreting it.
+func TestModules(t *testing.T) {
+	tests := []struct {
+		circuit  string
+		generics []int
+	}{
+		{"adder", nil},
+		{"addN", []int{3}},
+		{"sequence", nil},
+		{"once", nil},
+	}
+
+	for _, test := range tests {
+		mods, table := modules(t, test.circuit, test.generics...)
+		top := mods[len(mods)-1]
+
+		for _, row := range table.Rows {
+			in := row.Inputs
+
+			// the previous values of the outputs are all 0, like the
+			// registers in a simulation.
+			env := make(map[string][]bool)
+			for _, port := range top.Inputs {
+				if port.Kind == Data {
+					env[port.Name], in = in[:port.Width], in[port.Width:]
+				} else {
+					env[port.Name] = make([]bool, port.Width)
+				}
+			}
+
+			run(top, env)
+
+			var out []bool
+			for _, port := range top.Outputs {
+				out = append(out, env[port.Name]...)
+			}
+
+			for i := range out {
+				if out[i] != row.Outputs[i] {
+					t.Errorf("%s: inputs %v: expected %v, got %v", top.Circuit, row.Inputs, row.Outputs, out)
+					break
+				}
+			}
+		}
+	}
+}
+
+// run evaluates a module which doesn't have any clocks, setting the
+// values of its wires and outputs in env.
+func run(mod *Module, env map[string][]bool) {
+	set := func(ref Ref, value bool) {
+		i := ref.Index
+		if i < 0 {
+			i = 0
+		}
+
+		for len(env[ref.Name]) <= i {
+			env[ref.Name] = append(env[ref.Name], false)
+		}
+
+		env[ref.Name][i] = value
+	}
+
+	for _, stmt := range mod.Body {
+		switch st := stmt.(type) {
+		case *Assign:
+			set(st.Target, eval(st.Value, env))
+
+		case *Instance:
+			inner := make(map[string][]bool)
+
+			for _, conn := range st.Connections {
+				if conn.Port.Kind == Data && contains(st.Module.Outputs, conn.Port) {
+					continue
+				}
+
+				for _, value := range conn.Values {
+					inner[conn.Port.Name] = append(inner[conn.Port.Name], eval(value, env))
+				}
+			}
+
+			run(st.Module, inner)
+
+			for _, conn := range st.Connections {
+				if contains(st.Module.Outputs, conn.Port) {
+					for i, value := range conn.Values {
+						set(value.(Ref), inner[conn.Port.Name][i])
+					}
+				}
+			}
+		}
+	}
+}
+
+func contains(ports []*Port, port *Port) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+
+	return false
+}
+
+func eval(e Expr, env map[string][]bool) bool {
+	switch ex := e.(type) {
+	case Const:
+		return bool(ex)
+
+	case Ref:
+		if ex.Index < 0 {
+			return env[ex.Name][0]
+		}
+
+		return env[ex.Name][ex.Index]
+
+	case *Gate:
+		var args []bool
+		for _, arg := range ex.Args {
+			args = append(args, eval(arg, env))
+		}
+
+		switch ex.Op {
+		case netlist.Not:
+			return !args[0]
+		case netlist.And:
+			return args[0] && args[1]
+		case netlist.Or:
+			return args[0] || args[1]
+		case netlist.Xor:
+			return args[0] != args[1]
+		case netlist.Nand:
+			return !(args[0] && args[1])
+		case netlist.Nor:
+			return !(args[0] || args[1])
+		case netlist.Xnor:
+			return args[0] == args[1]
+		case netlist.Imp:
+			return !args[0] || args[1]
+		}
+	}
+
+	panic("unknown expression")
+}
+
+func TestVerilog(t *testing.T) {
+	mods, _ := modules(t, "blink")
+
+	var buf bytes.Buffer
+	if err := WriteVerilog(&buf, mods); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"module blink (",
+		"input wire clk_0 /* clock 1s at test",
+		"reg x = 1'b1;",
+		"assign x_v2 = ~x;",
+		"always @(posedge clk_0) begin",
+		"x <= x_v2;",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q in:\n%s", line, buf.String())
+		}
+	}
+
+	mods, _ = modules(t, "keep")
+
+	buf.Reset()
+	if err := WriteVerilog(&buf, mods); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "input wire o_in") {
+		t.Errorf("expected keep to read the previous value of o:\n%s", buf.String())
+	}
+}
+
+func TestVHDL(t *testing.T) {
+	mods, _ := modules(t, "addN", 2)
+	mods = append(mods, modulesOf(t, "blink")...)
+
+	var buf bytes.Buffer
+	if err := WriteVHDL(&buf, mods); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"entity addN_2 is",
+		"a : in std_logic_vector(1 downto 0);",
+		"component adder is",
+		"adder_inst_2 : adder",
+		"cin => c1_v1,",
+		"signal x : std_logic := '1';",
+		"if rising_edge(clk_0) then",
+		"entity blink_tb is",
+		"clk_0 <= not clk_0 after 500 ms when not done else '0';",
+		"wait for 10 sec;",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q in:\n%s", line, buf.String())
+		}
+	}
+}
+
+func modulesOf(t *testing.T, circuit string) []*Module {
+	mods, _ := modules(t, circuit)
+	return mods
+}
+
+// TestClash checks that a register written by two clocks, which
+// would be driven by two processes, can't be exported.
+func TestClash(t *testing.T) {
+	prog, err := parser.New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nets, err := netlist.ElaborateModules(prog, "clash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Modules(nets); err == nil || !strings.Contains(err.Error(), "register 'x' is also written by the clock at") {
+		t.Errorf("expected an error about x, got %v", err)
+	}
+}
+
+// TestVHDLDrivers checks that each signal written by a clock is only
+// driven by that clock's process, since a signal with two drivers
+// resolves to 'X'.
+func TestVHDLDrivers(t *testing.T) {
+	mods, _ := modules(t, "shift")
+
+	var buf bytes.Buffer
+	if err := WriteVHDL(&buf, mods); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"x <= x_v1;", "y <= y_v1;"} {
+		if n := strings.Count(buf.String(), line); n != 1 {
+			t.Errorf("expected %q once, got it %d times in:\n%s", line, n, buf.String())
+		}
+	}
+
+	prog, err := parser.New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nets, err := netlist.ElaborateModules(prog, "clash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mods, err := Modules(nets); err == nil {
+		buf.Reset()
+		WriteVHDL(&buf, mods)
+		t.Errorf("expected x to be rejected, got:\n%s", buf.String())
+	}
+}
+
+// TestKeepsState checks that a circuit which keeps a register's value
+// between calls, which the simulator does, can't be exported when
+// it's called inside a clock, where it would lose the value.
+func TestKeepsState(t *testing.T) {
+	prog, err := parser.New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	net, err := netlist.ElaborateCircuit(prog, "ticking")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := sim.FromNetlist(net)
+
+	for _, expected := range []bool{true, false, true} {
+		if err := s.Step(time.Second); err != nil {
+			t.Fatal(err)
+		}
+
+		if o, _ := s.Get("o"); o != expected {
+			t.Fatalf("expected the simulator to toggle o, but it's %t at %s", o, s.Time())
+		}
+	}
+
+	nets, err := netlist.ElaborateModules(prog, "ticking")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Modules(nets); err == nil || !strings.Contains(err.Error(), "keeps the value of 's'") {
+		t.Errorf("expected ticking to be rejected, got %v", err)
+	}
+}
+
+func TestVHDLPeriods(t *testing.T) {
+	mods, _ := modules(t, "fast")
+
+	var buf bytes.Buffer
+	if err := WriteVHDL(&buf, mods); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"clk_0 <= not clk_0 after 500 ps when not done else '0';",
+		"clk_1 <= not clk_1 after 1500 ps when not done else '0';",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q in:\n%s", line, buf.String())
+		}
+	}
+
+	// a gate which can't be written is an error, rather than
+	// something which isn't valid VHDL.
+	mod := &Module{
+		Name: "broken",
+		Body: []Stmt{
+			&Assign{Target: Ref{"o", -1}, Value: &Gate{Op: netlist.Read}},
+		},
+	}
+
+	if err := WriteVHDL(&buf, []*Module{mod}); err == nil {
+		t.Errorf("expected an error for a read gate")
+	}
+}
@@ -0,0 +1,808 @@
+// Package hdl exports booleang circuits to hardware description
+// languages, keeping each circuit as a separate module.
+//
+// Registers in booleang are written by a sequence of statements,
+// while signals in an HDL are driven continuously. So each time a
+// register is written, the new value is given to a new wire, and
+// later statements read that wire instead. The registers written by
+// clocks hold state between ticks, so they become real registers
+// which are updated on the rising edges of the clocks.
+package hdl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zac-garby/booleang/netlist"
+)
+
+// An Expr is a single-bit expression.
+type Expr interface {
+	expr()
+}
+
+type (
+	// A Const is a constant bit.
+	Const bool
+
+	// A Ref is a signal, or a bit of one. Index is -1 if the signal
+	// isn't a bus.
+	Ref struct {
+		Name  string
+		Index int
+	}
+
+	// A Gate applies an operation to some expressions.
+	Gate struct {
+		Op   netlist.Op
+		Args []Expr
+	}
+)
+
+func (Const) expr() {}
+func (Ref) expr()   {}
+func (*Gate) expr() {}
+
+// A PortKind says what a port is for.
+type PortKind int
+
+// The kinds of port a module can have.
+const (
+	// Data ports are the inputs and outputs of the circuit.
+	Data PortKind = iota
+
+	// A Previous port gives a module the value an output had before
+	// the module was called, because in booleang the outputs of a
+	// circuit are its caller's registers. It's only added if the
+	// module reads an output before writing it, or never writes it.
+	Previous
+
+	// A Clock port is the clock of a clock statement.
+	Clock
+
+	// A Read port is the value read by the input builtin.
+	Read
+)
+
+// A Port is one of the ports of a module.
+type Port struct {
+	Name  string
+	Width int
+	Kind  PortKind
+
+	// Bus is set if the port is a bus, even if it's one bit wide.
+	Bus bool
+
+	// Period is the period of a clock port.
+	Period time.Duration
+
+	// Label describes where a clock or read port came from, or
+	// names the output of a Previous port.
+	Label string
+}
+
+// A Module is the HDL version of the netlist of a single circuit.
+type Module struct {
+	Name string
+
+	// Circuit is the name of the netlist's module, e.g. addN<4>.
+	Circuit string
+
+	Inputs, Outputs []*Port
+
+	// Wires are the names of the single-bit wires declared in the
+	// module, and Regs are its registers.
+	Wires []string
+	Regs  []*Reg
+
+	// Registers maps the name of each wire and reg to the name of
+	// the booleang register it holds a value of, e.g. c1_v1 to c1.
+	Registers map[string]string
+
+	// Body contains the assignments and instances in the module,
+	// in the order they appear in the circuit.
+	Body []Stmt
+
+	// Processes update the registers each time a clock ticks.
+	Processes []*Process
+
+	Watches []*Watch
+
+	// keeps names a register which keeps its value from one call of
+	// the circuit to the next, because it's read before it's written,
+	// or is empty if there isn't one.
+	keeps string
+}
+
+// A Stmt is either an Assign or an Instance.
+type Stmt interface {
+	stmt()
+}
+
+// An Assign continuously drives a wire or output.
+type Assign struct {
+	Target Ref
+	Value  Expr
+}
+
+// An Instance is an instance of another module.
+type Instance struct {
+	Name        string
+	Module      *Module
+	Connections []Connection
+}
+
+func (*Assign) stmt()   {}
+func (*Instance) stmt() {}
+
+// A Connection connects each bit of one of a module's ports to an
+// expression, or, for an output, to a wire.
+type Connection struct {
+	Port   *Port
+	Values []Expr
+}
+
+// A Reg holds a bit of state, which is written by a clock.
+type Reg struct {
+	Name string
+
+	// Init is the register's value once the statements outside of
+	// any clock have run. If it's a Const, it doesn't depend on
+	// anything else in the module.
+	Init Expr
+}
+
+// A Process updates some registers when a clock ticks.
+type Process struct {
+	Clock   *Port
+	Updates []Update
+}
+
+// An Update sets a register to a new value.
+type Update struct {
+	Reg   string
+	Value Expr
+}
+
+// A Watch is a call to an output builtin, which has no equivalent in
+// an HDL, so it's just written as a comment.
+type Watch struct {
+	Builtin, Label string
+	Values         []Expr
+}
+
+// Modules converts a list of netlists into modules. Like the result
+// of netlist.ElaborateModules, each netlist must come after all the
+// netlists it has instances of.
+func Modules(nets []*netlist.Netlist) ([]*Module, error) {
+	var (
+		modules  []*Module
+		byName   = make(map[string]*Module)
+		allNames = newNames()
+	)
+
+	for _, net := range nets {
+		b := &builder{
+			net:     net,
+			modules: byName,
+			names:   newNames(),
+			mod: &Module{
+				Name:      allNames.unique(net.Module()),
+				Circuit:   net.Module(),
+				Registers: make(map[string]string),
+			},
+		}
+
+		if err := b.build(); err != nil {
+			return nil, err
+		}
+
+		modules = append(modules, b.mod)
+		byName[net.Module()] = b.mod
+	}
+
+	return modules, nil
+}
+
+// A builder converts one netlist into a module.
+type builder struct {
+	net     *netlist.Netlist
+	mod     *Module
+	modules map[string]*Module
+	names   *names
+
+	// bits maps each register of a port to its port and index.
+	bits map[int]Ref
+
+	// outputs are the module's output ports, and previous maps the
+	// name of each one to its Previous port, if it's been read.
+	outputs  []*Port
+	previous map[string]*Port
+
+	// state contains the registers which are written by clocks, and
+	// their names, and clocks maps each of them to the index of the
+	// clock which writes it.
+	state  map[int]string
+	clocks map[int]int
+
+	// current maps each register which has been written to its
+	// current value, and versions counts how many times each one
+	// has been written.
+	current  map[int]Expr
+	versions map[int]int
+
+	// unset contains the registers which have been read before being
+	// written, and inClock is set while a clock's body is built.
+	unset   map[int]bool
+	inClock bool
+
+	// wires holds the value of each wire.
+	wires map[string]Expr
+}
+
+func (b *builder) build() error {
+	b.bits = make(map[int]Ref)
+	b.previous = make(map[string]*Port)
+	b.state = make(map[int]string)
+	b.clocks = make(map[int]int)
+	b.current = make(map[int]Expr)
+	b.versions = make(map[int]int)
+	b.unset = make(map[int]bool)
+	b.wires = make(map[string]Expr)
+
+	b.mod.Inputs = b.ports(b.net.Inputs)
+	b.outputs = b.ports(b.net.Outputs)
+
+	for i, clock := range b.net.Clocks {
+		if err := b.written(i, clock.Body); err != nil {
+			return err
+		}
+	}
+
+	for reg := range b.net.Registers {
+		if name, ok := b.state[reg]; ok {
+			b.state[reg] = b.names.unique(name)
+			b.mod.Registers[b.state[reg]] = name
+		}
+	}
+
+	if err := b.steps(b.net.Init); err != nil {
+		return err
+	}
+
+	// a register which is read as 0 and then written would have
+	// its new value the next time the circuit is called, which a
+	// module can only do if it's called from a clock.
+	for reg := range b.net.Registers {
+		if _, written := b.current[reg]; written && b.unset[reg] && b.mod.keeps == "" {
+			b.mod.keeps = b.net.Registers[reg].Name
+		}
+	}
+
+	// a register written by a clock starts with the value it has
+	// after everything outside of the clocks has run. Everything
+	// else keeps that value forever.
+	final := b.current
+	b.current = make(map[int]Expr)
+
+	for reg := range b.net.Registers {
+		if name, ok := b.state[reg]; ok {
+			b.mod.Regs = append(b.mod.Regs, &Reg{
+				Name: name,
+				Init: b.fold(b.read(reg, final)),
+			})
+
+			b.current[reg] = Ref{name, -1}
+		} else if value, ok := final[reg]; ok {
+			b.current[reg] = value
+		}
+	}
+
+	after := b.current
+	b.inClock = true
+
+	for i, clock := range b.net.Clocks {
+		port := &Port{
+			Name:   b.names.unique(fmt.Sprintf("clk_%d", i)),
+			Width:  1,
+			Kind:   Clock,
+			Period: clock.Period,
+			Label:  fmt.Sprintf("clock %s at %s", clock.Period, clock.Range),
+		}
+
+		b.mod.Inputs = append(b.mod.Inputs, port)
+
+		b.current = make(map[int]Expr, len(after))
+		for reg, value := range after {
+			b.current[reg] = value
+		}
+
+		if err := b.steps(clock.Body); err != nil {
+			return err
+		}
+
+		process := &Process{Clock: port}
+
+		for reg := range b.net.Registers {
+			if name, ok := b.state[reg]; ok && b.current[reg] != after[reg] {
+				process.Updates = append(process.Updates, Update{
+					Reg:   name,
+					Value: b.current[reg],
+				})
+			}
+		}
+
+		b.mod.Processes = append(b.mod.Processes, process)
+	}
+
+	b.current = after
+	b.inClock = false
+
+	for _, reg := range b.net.Outputs {
+		b.mod.Body = append(b.mod.Body, &Assign{
+			Target: b.bits[reg],
+			Value:  b.read(reg, b.current),
+		})
+	}
+
+	for _, port := range b.outputs {
+		if prev, ok := b.previous[port.Name]; ok {
+			b.mod.Inputs = append(b.mod.Inputs, prev)
+		}
+	}
+
+	b.mod.Outputs = b.outputs
+
+	for _, watch := range b.net.Watches {
+		w := &Watch{
+			Builtin: watch.Builtin,
+			Label:   watch.Label,
+		}
+
+		for _, node := range watch.Nodes {
+			w.Values = append(w.Values, b.expr(node))
+		}
+
+		b.mod.Watches = append(b.mod.Watches, w)
+	}
+
+	return nil
+}
+
+// ports groups the registers of a circuit's parameters into ports,
+// so the bits of a bus share a port.
+func (b *builder) ports(regs []int) []*Port {
+	var ports []*Port
+
+	for _, reg := range regs {
+		name := b.net.Registers[reg].Name
+		bus, i, ok := busBit(name)
+
+		if ok && len(ports) > 0 && ports[len(ports)-1].Bus && ports[len(ports)-1].Label == bus && i == ports[len(ports)-1].Width {
+			port := ports[len(ports)-1]
+			port.Width++
+			b.bits[reg] = Ref{port.Name, i}
+			continue
+		}
+
+		port := &Port{
+			Width: 1,
+			Label: name,
+		}
+
+		if ok && i == 0 {
+			port.Bus = true
+			port.Label = bus
+		}
+
+		port.Name = b.names.unique(port.Label)
+		ports = append(ports, port)
+
+		if port.Bus {
+			b.bits[reg] = Ref{port.Name, 0}
+		} else {
+			b.bits[reg] = Ref{port.Name, -1}
+		}
+	}
+
+	// the labels were only needed to find the bits of each bus.
+	for _, port := range ports {
+		port.Label = ""
+	}
+
+	return ports
+}
+
+// written finds the registers written by the steps of a clock, which
+// hold state because the steps are in a clock. An HDL register can
+// only be driven by one process, so it's an error for a register to
+// be written by more than one clock.
+func (b *builder) written(clock int, steps []netlist.Step) error {
+	var regs []int
+
+	for _, step := range steps {
+		switch st := step.(type) {
+		case *netlist.Assign:
+			regs = append(regs, st.Targets...)
+		case *netlist.Input:
+			regs = append(regs, st.Targets...)
+		case *netlist.Instance:
+			regs = append(regs, st.Outputs...)
+		}
+	}
+
+	for _, reg := range regs {
+		if other, ok := b.clocks[reg]; ok && other != clock {
+			return fmt.Errorf(
+				"[%s] register '%s' is also written by the clock at [%s]. it can only be exported if one clock writes it",
+				b.net.Clocks[clock].Range, b.net.Registers[reg].Name, b.net.Clocks[other].Range,
+			)
+		}
+
+		b.clocks[reg] = clock
+		b.state[reg] = b.net.Registers[reg].Name
+	}
+
+	return nil
+}
+
+func (b *builder) steps(steps []netlist.Step) error {
+	for _, step := range steps {
+		switch st := step.(type) {
+		case *netlist.Assign:
+			values := make([]Expr, len(st.Sources))
+			for i, src := range st.Sources {
+				values[i] = b.expr(src)
+			}
+
+			for i, reg := range st.Targets {
+				wire := b.wire(reg)
+				b.wires[wire] = values[i]
+
+				b.mod.Body = append(b.mod.Body, &Assign{
+					Target: Ref{wire, -1},
+					Value:  values[i],
+				})
+			}
+
+		case *netlist.Input:
+			port := &Port{
+				Name:  b.names.unique(fmt.Sprintf("read_%d", b.reads())),
+				Width: len(st.Targets),
+				Kind:  Read,
+				Bus:   true,
+				Label: fmt.Sprintf("%s at %s", st.Label, st.Range),
+			}
+
+			b.mod.Inputs = append(b.mod.Inputs, port)
+
+			for i, reg := range st.Targets {
+				b.current[reg] = Ref{port.Name, i}
+			}
+
+		case *netlist.Instance:
+			if err := b.instance(st); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("cannot export a step of type %T", step)
+		}
+	}
+
+	return nil
+}
+
+// reads counts the read ports the module has so far.
+func (b *builder) reads() int {
+	n := 0
+
+	for _, port := range b.mod.Inputs {
+		if port.Kind == Read {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (b *builder) instance(st *netlist.Instance) error {
+	mod, ok := b.modules[st.Module]
+	if !ok {
+		return fmt.Errorf("[%s] module '%s' hasn't been built", st.Range, st.Module)
+	}
+
+	if mod.keeps != "" {
+		if b.inClock {
+			return fmt.Errorf(
+				"[%s] '%s' can't be exported when it's called inside a clock, since it keeps the value of '%s' between calls",
+				st.Range, st.Module, mod.keeps,
+			)
+		}
+
+		if b.mod.keeps == "" {
+			b.mod.keeps = st.Module + "." + mod.keeps
+		}
+	}
+
+	inst := &Instance{
+		Name:   b.names.unique(mod.Name + "_inst"),
+		Module: mod,
+	}
+
+	var (
+		inputs = st.Inputs
+		prev   = make(map[string][]Expr)
+		outs   = st.Outputs
+	)
+
+	// the previous values of the outputs are read before any of
+	// them are written, but only if the module uses them.
+	for _, port := range mod.Outputs {
+		regs := outs[:port.Width]
+		outs = outs[port.Width:]
+
+		if !mod.hasPrevious(port) {
+			continue
+		}
+
+		for _, reg := range regs {
+			prev[port.Name] = append(prev[port.Name], b.read(reg, b.current))
+		}
+	}
+
+	for _, port := range mod.Inputs {
+		conn := Connection{Port: port}
+
+		switch port.Kind {
+		case Data:
+			for i, node := range inputs[:port.Width] {
+				value := b.expr(node)
+
+				// some languages only allow signals and constants to
+				// be connected to ports, so gates are given a wire.
+				if _, ok := value.(*Gate); ok {
+					name := inst.Name + "_" + port.Name
+					if port.Bus {
+						name = fmt.Sprintf("%s_%d", name, i)
+					}
+
+					wire := b.names.unique(name)
+					b.mod.Wires = append(b.mod.Wires, wire)
+					b.wires[wire] = value
+					b.mod.Body = append(b.mod.Body, &Assign{
+						Target: Ref{wire, -1},
+						Value:  value,
+					})
+
+					value = Ref{wire, -1}
+				}
+
+				conn.Values = append(conn.Values, value)
+			}
+
+			inputs = inputs[port.Width:]
+
+		case Previous:
+			conn.Values = prev[port.Label]
+
+		case Clock, Read:
+			// the ports of the instance's clocks and reads become
+			// ports of this module too.
+			outer := &Port{
+				Name:   b.names.unique(inst.Name + "_" + port.Name),
+				Width:  port.Width,
+				Kind:   port.Kind,
+				Bus:    port.Bus,
+				Period: port.Period,
+				Label:  port.Label,
+			}
+
+			b.mod.Inputs = append(b.mod.Inputs, outer)
+
+			for i := 0; i < port.Width; i++ {
+				if port.Bus {
+					conn.Values = append(conn.Values, Ref{outer.Name, i})
+				} else {
+					conn.Values = append(conn.Values, Ref{outer.Name, -1})
+				}
+			}
+		}
+
+		inst.Connections = append(inst.Connections, conn)
+	}
+
+	outs = st.Outputs
+
+	for _, port := range mod.Outputs {
+		conn := Connection{Port: port}
+
+		for _, reg := range outs[:port.Width] {
+			conn.Values = append(conn.Values, Ref{b.wire(reg), -1})
+		}
+
+		outs = outs[port.Width:]
+		inst.Connections = append(inst.Connections, conn)
+	}
+
+	b.mod.Body = append(b.mod.Body, inst)
+
+	return nil
+}
+
+// hasPrevious checks whether a module has a Previous port for one of
+// its outputs.
+func (m *Module) hasPrevious(output *Port) bool {
+	for _, port := range m.Inputs {
+		if port.Kind == Previous && port.Label == output.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wire declares a new wire for the next value of a register, and
+// makes it the register's current value.
+func (b *builder) wire(reg int) string {
+	b.versions[reg]++
+
+	name := b.names.unique(fmt.Sprintf("%s_v%d", b.net.Registers[reg].Name, b.versions[reg]))
+	b.mod.Wires = append(b.mod.Wires, name)
+	b.mod.Registers[name] = b.net.Registers[reg].Name
+	b.current[reg] = Ref{name, -1}
+
+	return name
+}
+
+// read returns the current value of a register. A register which
+// hasn't been written yet is 0, unless it's a parameter.
+func (b *builder) read(reg int, current map[int]Expr) Expr {
+	if value, ok := current[reg]; ok {
+		return value
+	}
+
+	for _, in := range b.net.Inputs {
+		if in == reg {
+			return b.bits[reg]
+		}
+	}
+
+	for _, out := range b.net.Outputs {
+		if out == reg {
+			return b.previousBit(reg)
+		}
+	}
+
+	b.unset[reg] = true
+
+	return Const(false)
+}
+
+// previousBit returns the bit of a Previous port which holds the
+// value of an output before the module was called.
+func (b *builder) previousBit(reg int) Expr {
+	bit := b.bits[reg]
+
+	port, ok := b.previous[bit.Name]
+	if !ok {
+		for _, out := range b.outputs {
+			if out.Name != bit.Name {
+				continue
+			}
+
+			port = &Port{
+				Name:  b.names.unique(out.Name + "_in"),
+				Width: out.Width,
+				Kind:  Previous,
+				Bus:   out.Bus,
+				Label: out.Name,
+			}
+		}
+
+		b.previous[bit.Name] = port
+	}
+
+	return Ref{port.Name, bit.Index}
+}
+
+// expr converts a node into an expression, using the current values
+// of the registers it reads.
+func (b *builder) expr(id int) Expr {
+	node := b.net.Nodes[id]
+
+	switch node.Op {
+	case netlist.Const:
+		return Const(node.Value)
+
+	case netlist.Read:
+		return b.read(node.Register, b.current)
+	}
+
+	gate := &Gate{Op: node.Op}
+	for _, arg := range node.Args {
+		gate.Args = append(gate.Args, b.expr(arg))
+	}
+
+	return gate
+}
+
+// fold evaluates an expression if it's constant, following wires to
+// their values, and otherwise returns it as it is.
+func (b *builder) fold(e Expr) Expr {
+	if value, ok := b.constant(e); ok {
+		return Const(value)
+	}
+
+	return e
+}
+
+func (b *builder) constant(e Expr) (bool, bool) {
+	switch ex := e.(type) {
+	case Const:
+		return bool(ex), true
+
+	case Ref:
+		if value, ok := b.wires[ex.Name]; ok && ex.Index < 0 {
+			return b.constant(value)
+		}
+
+		return false, false
+
+	case *Gate:
+		var args []bool
+
+		for _, arg := range ex.Args {
+			value, ok := b.constant(arg)
+			if !ok {
+				return false, false
+			}
+
+			args = append(args, value)
+		}
+
+		return eval(ex.Op, args), true
+	}
+
+	return false, false
+}
+
+func eval(op netlist.Op, args []bool) bool {
+	switch op {
+	case netlist.Not:
+		return !args[0]
+	case netlist.And:
+		return args[0] && args[1]
+	case netlist.Or:
+		return args[0] || args[1]
+	case netlist.Xor:
+		return args[0] != args[1]
+	case netlist.Nand:
+		return !(args[0] && args[1])
+	case netlist.Nor:
+		return !(args[0] || args[1])
+	case netlist.Xnor:
+		return args[0] == args[1]
+	case netlist.Imp:
+		return !args[0] || args[1]
+	}
+
+	return false
+}
+
+// busBit splits a register's name into the name of its bus and its
+// index, e.g. a[3] into a and 3.
+func busBit(name string) (string, int, bool) {
+	open := strings.IndexByte(name, '[')
+	if open < 0 || !strings.HasSuffix(name, "]") {
+		return "", 0, false
+	}
+
+	var i int
+	if _, err := fmt.Sscanf(name[open+1:len(name)-1], "%d", &i); err != nil {
+		return "", 0, false
+	}
+
+	return name[:open], i, true
+}
@@ -0,0 +1,89 @@
+package hdl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keywords are reserved words in Verilog or VHDL, which can't be
+// used as names in either. Since VHDL isn't case sensitive, names
+// are compared in lower case.
+var keywords = make(map[string]bool)
+
+func init() {
+	for _, word := range strings.Fields(`
+		always and assign begin buf case default else end endcase
+		endfunction endmodule endtask for function generate if initial
+		inout input integer module nand negedge nor not or output
+		parameter posedge reg task wire xnor xor
+
+		abs access after alias all architecture array assert attribute
+		block body buffer bus component configuration constant
+		disconnect downto elsif entity exit file generic group guarded
+		impure in inertial is label library linkage literal loop map
+		mod new next null of on open others out package port postponed
+		procedure process pure range record register reject rem report
+		return rol ror select severity shared signal sla sll sra srl
+		subtype then to transport type unaffected units until use
+		variable wait when while with
+	`) {
+		keywords[word] = true
+	}
+}
+
+// names hands out names which are unique within a module, and are
+// valid identifiers in both Verilog and VHDL.
+type names struct {
+	used map[string]bool
+}
+
+func newNames() *names {
+	return &names{
+		used: make(map[string]bool),
+	}
+}
+
+// unique makes a name based on the given one which hasn't been used
+// yet, replacing any characters which can't be used in identifiers.
+func (n *names) unique(base string) string {
+	base = sanitise(base)
+
+	name := base
+	for i := 2; n.used[strings.ToLower(name)] || keywords[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+
+	n.used[strings.ToLower(name)] = true
+
+	return name
+}
+
+// sanitise replaces each run of characters which aren't letters or
+// digits with a single underscore, e.g. a[3] with a_3. VHDL doesn't
+// allow underscores at either end of a name, or two in a row.
+func sanitise(name string) string {
+	var (
+		b          strings.Builder
+		underscore bool
+	)
+
+	for _, r := range name {
+		if r < 128 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			if underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+
+			b.WriteRune(r)
+			underscore = false
+		} else {
+			underscore = true
+		}
+	}
+
+	s := b.String()
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		s = "n" + s
+	}
+
+	return s
+}
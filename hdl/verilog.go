@@ -0,0 +1,207 @@
+package hdl
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/zac-garby/booleang/netlist"
+)
+
+// WriteVerilog writes each module as a Verilog module. The modules
+// should be in the order returned by Modules, so each one is written
+// after the modules it has instances of.
+func WriteVerilog(w io.Writer, modules []*Module) error {
+	var b strings.Builder
+
+	for i, mod := range modules {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		verilogModule(&b, mod)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func verilogModule(b *strings.Builder, mod *Module) {
+	fmt.Fprintf(b, "// %s\n", mod.Circuit)
+
+	var ports []string
+
+	for _, port := range mod.Inputs {
+		ports = append(ports, verilogPort("input", port))
+	}
+
+	for _, port := range mod.Outputs {
+		ports = append(ports, verilogPort("output", port))
+	}
+
+	if len(ports) == 0 {
+		fmt.Fprintf(b, "module %s;\n", mod.Name)
+	} else {
+		fmt.Fprintf(b, "module %s (\n    %s\n);\n", mod.Name, strings.Join(ports, ",\n    "))
+	}
+
+	if len(mod.Wires) > 0 {
+		b.WriteString("\n")
+
+		for _, wire := range mod.Wires {
+			fmt.Fprintf(b, "    wire %s;\n", wire)
+		}
+	}
+
+	if len(mod.Regs) > 0 {
+		b.WriteString("\n")
+
+		for _, reg := range mod.Regs {
+			if init, ok := reg.Init.(Const); ok {
+				fmt.Fprintf(b, "    reg %s = %s;\n", reg.Name, verilogExpr(init))
+			} else {
+				fmt.Fprintf(b, "    reg %s;\n", reg.Name)
+				fmt.Fprintf(b, "    initial %s = %s;\n", reg.Name, verilogExpr(reg.Init))
+			}
+		}
+	}
+
+	if len(mod.Body) > 0 {
+		b.WriteString("\n")
+	}
+
+	for _, stmt := range mod.Body {
+		switch st := stmt.(type) {
+		case *Assign:
+			fmt.Fprintf(b, "    assign %s = %s;\n", verilogExpr(st.Target), verilogExpr(st.Value))
+
+		case *Instance:
+			var conns []string
+
+			for _, conn := range st.Connections {
+				conns = append(conns, fmt.Sprintf(".%s(%s)", conn.Port.Name, verilogConcat(conn.Values)))
+			}
+
+			fmt.Fprintf(
+				b,
+				"    %s %s (\n        %s\n    );\n",
+				st.Module.Name,
+				st.Name,
+				strings.Join(conns, ",\n        "),
+			)
+		}
+	}
+
+	for _, proc := range mod.Processes {
+		fmt.Fprintf(b, "\n    // %s\n", proc.Clock.Label)
+		fmt.Fprintf(b, "    always @(posedge %s) begin\n", proc.Clock.Name)
+
+		for _, update := range proc.Updates {
+			fmt.Fprintf(b, "        %s <= %s;\n", update.Reg, verilogExpr(update.Value))
+		}
+
+		b.WriteString("    end\n")
+	}
+
+	if len(mod.Watches) > 0 {
+		b.WriteString("\n")
+	}
+
+	for _, watch := range mod.Watches {
+		var values []string
+		for _, value := range watch.Values {
+			values = append(values, verilogExpr(value))
+		}
+
+		fmt.Fprintf(b, "    // %s(%s): %s\n", watch.Builtin, watch.Label, strings.Join(values, ", "))
+	}
+
+	b.WriteString("endmodule\n")
+}
+
+func verilogPort(dir string, port *Port) string {
+	decl := fmt.Sprintf("%s wire %s", dir, port.Name)
+	if port.Bus {
+		decl = fmt.Sprintf("%s wire [%d:0] %s", dir, port.Width-1, port.Name)
+	}
+
+	switch port.Kind {
+	case Clock:
+		decl += fmt.Sprintf(" /* %s */", port.Label)
+	case Read:
+		decl += fmt.Sprintf(" /* input %s */", port.Label)
+	}
+
+	return decl
+}
+
+// verilogConcat joins the bits of a port, which are least significant
+// first, into a concatenation, which is most significant first.
+func verilogConcat(values []Expr) string {
+	if len(values) == 1 {
+		return verilogExpr(values[0])
+	}
+
+	var parts []string
+	for i := len(values) - 1; i >= 0; i-- {
+		parts = append(parts, verilogExpr(values[i]))
+	}
+
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+var verilogOps = map[netlist.Op]string{
+	netlist.And: "&",
+	netlist.Or:  "|",
+	netlist.Xor: "^",
+}
+
+// verilogInverted maps the operations which don't have an operator in
+// Verilog to the operations they're the inverse of.
+var verilogInverted = map[netlist.Op]netlist.Op{
+	netlist.Nand: netlist.And,
+	netlist.Nor:  netlist.Or,
+	netlist.Xnor: netlist.Xor,
+}
+
+func verilogExpr(e Expr) string {
+	switch ex := e.(type) {
+	case Const:
+		if ex {
+			return "1'b1"
+		}
+
+		return "1'b0"
+
+	case Ref:
+		if ex.Index < 0 {
+			return ex.Name
+		}
+
+		return fmt.Sprintf("%s[%d]", ex.Name, ex.Index)
+
+	case *Gate:
+		var args []string
+		for _, arg := range ex.Args {
+			args = append(args, verilogExpr(arg))
+		}
+
+		switch ex.Op {
+		case netlist.Not:
+			return "~" + args[0]
+
+		case netlist.Imp:
+			return fmt.Sprintf("(~%s | %s)", args[0], args[1])
+		}
+
+		if op, ok := verilogOps[ex.Op]; ok {
+			return fmt.Sprintf("(%s %s %s)", args[0], op, args[1])
+		}
+
+		if op, ok := verilogInverted[ex.Op]; ok {
+			return fmt.Sprintf("~(%s %s %s)", args[0], verilogOps[op], args[1])
+		}
+	}
+
+	return fmt.Sprintf("/* unknown expression %T */", e)
+}
@@ -0,0 +1,372 @@
+package hdl
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/zac-garby/booleang/netlist"
+)
+
+const vhdlHeader = "library ieee;\nuse ieee.std_logic_1164.all;\n"
+
+// WriteVHDL writes each module as a VHDL entity and architecture,
+// followed by a testbench for the last module, which drives its
+// clocks for ten of the slowest one's periods.
+func WriteVHDL(w io.Writer, modules []*Module) error {
+	var b strings.Builder
+
+	for i, mod := range modules {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		if err := vhdlEntity(&b, mod); err != nil {
+			return err
+		}
+	}
+
+	if len(modules) > 0 {
+		b.WriteString("\n")
+		vhdlTestbench(&b, modules[len(modules)-1])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func vhdlEntity(b *strings.Builder, mod *Module) error {
+	fmt.Fprintf(b, "%s\n-- %s\nentity %s is\n", vhdlHeader, mod.Circuit, mod.Name)
+	vhdlPorts(b, mod, "    ")
+	b.WriteString("end entity;\n\n")
+
+	fmt.Fprintf(b, "architecture rtl of %s is\n", mod.Name)
+
+	declared := make(map[*Module]bool)
+
+	for _, stmt := range mod.Body {
+		inst, ok := stmt.(*Instance)
+		if !ok || declared[inst.Module] {
+			continue
+		}
+
+		declared[inst.Module] = true
+
+		fmt.Fprintf(b, "    component %s is\n", inst.Module.Name)
+		vhdlPorts(b, inst.Module, "        ")
+		b.WriteString("    end component;\n\n")
+	}
+
+	for _, wire := range mod.Wires {
+		fmt.Fprintf(b, "    signal %s : std_logic;\n", wire)
+	}
+
+	for _, reg := range mod.Regs {
+		init, err := vhdlExpr(reg.Init)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := reg.Init.(Const); ok {
+			fmt.Fprintf(b, "    signal %s : std_logic := %s;\n", reg.Name, init)
+		} else {
+			// a signal's initial value has to be constant.
+			fmt.Fprintf(b, "    signal %s : std_logic := '0'; -- starts as %s\n", reg.Name, init)
+		}
+	}
+
+	b.WriteString("begin\n")
+
+	for _, stmt := range mod.Body {
+		switch st := stmt.(type) {
+		case *Assign:
+			value, err := vhdlExpr(st.Value)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(b, "    %s <= %s;\n", vhdlRef(st.Target), value)
+
+		case *Instance:
+			var assocs []string
+
+			for _, conn := range st.Connections {
+				for i, value := range conn.Values {
+					formal := conn.Port.Name
+					if conn.Port.Bus {
+						formal = fmt.Sprintf("%s(%d)", formal, i)
+					}
+
+					actual, err := vhdlExpr(value)
+					if err != nil {
+						return err
+					}
+
+					assocs = append(assocs, fmt.Sprintf("%s => %s", formal, actual))
+				}
+			}
+
+			fmt.Fprintf(
+				b,
+				"    %s : %s\n        port map (\n            %s\n        );\n",
+				st.Name,
+				st.Module.Name,
+				strings.Join(assocs, ",\n            "),
+			)
+		}
+	}
+
+	for _, proc := range mod.Processes {
+		fmt.Fprintf(b, "\n    -- %s\n", proc.Clock.Label)
+		fmt.Fprintf(b, "    process (%s)\n    begin\n", proc.Clock.Name)
+		fmt.Fprintf(b, "        if rising_edge(%s) then\n", proc.Clock.Name)
+
+		for _, update := range proc.Updates {
+			value, err := vhdlExpr(update.Value)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(b, "            %s <= %s;\n", update.Reg, value)
+		}
+
+		b.WriteString("        end if;\n    end process;\n")
+	}
+
+	if len(mod.Watches) > 0 {
+		b.WriteString("\n")
+	}
+
+	for _, watch := range mod.Watches {
+		var values []string
+		for _, value := range watch.Values {
+			v, err := vhdlExpr(value)
+			if err != nil {
+				return err
+			}
+
+			values = append(values, v)
+		}
+
+		fmt.Fprintf(b, "    -- %s(%s): %s\n", watch.Builtin, watch.Label, strings.Join(values, ", "))
+	}
+
+	b.WriteString("end architecture;\n")
+
+	return nil
+}
+
+// vhdlPorts writes the port clause of an entity or component, which
+// is left out if there aren't any ports.
+func vhdlPorts(b *strings.Builder, mod *Module, indent string) {
+	var ports []string
+
+	for _, port := range mod.Inputs {
+		ports = append(ports, fmt.Sprintf("%s : in %s", port.Name, vhdlType(port)))
+	}
+
+	for _, port := range mod.Outputs {
+		ports = append(ports, fmt.Sprintf("%s : out %s", port.Name, vhdlType(port)))
+	}
+
+	if len(ports) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "%sport (\n%s    %s\n%s);\n", indent, indent, strings.Join(ports, ";\n"+indent+"    "), indent)
+}
+
+func vhdlType(port *Port) string {
+	if port.Bus {
+		return fmt.Sprintf("std_logic_vector(%d downto 0)", port.Width-1)
+	}
+
+	return "std_logic"
+}
+
+// vhdlTestbench writes an entity with no ports, which instantiates a
+// module, drives its clocks, and stops them after a while. Its other
+// inputs are all 0, and are left for the user to set.
+func vhdlTestbench(b *strings.Builder, mod *Module) {
+	var (
+		names   = newNames()
+		name    = names.unique(mod.Name + "_tb")
+		longest time.Duration
+		signals []string
+		assocs  []string
+		clocks  []*Port
+	)
+
+	for _, port := range append(append([]*Port{}, mod.Inputs...), mod.Outputs...) {
+		names.unique(port.Name)
+
+		decl := fmt.Sprintf("signal %s : %s", port.Name, vhdlType(port))
+
+		if contains(mod.Inputs, port) {
+			if port.Bus {
+				decl += " := (others => '0')"
+			} else {
+				decl += " := '0'"
+			}
+		}
+
+		signals = append(signals, decl+";")
+		assocs = append(assocs, fmt.Sprintf("%s => %s", port.Name, port.Name))
+
+		if port.Kind == Clock {
+			clocks = append(clocks, port)
+
+			if port.Period > longest {
+				longest = port.Period
+			}
+		}
+	}
+
+	var (
+		done     = names.unique("done")
+		dut      = names.unique("dut")
+		stimulus = names.unique("stimulus")
+	)
+
+	duration := 10 * longest
+	if duration == 0 {
+		duration = 10 * time.Nanosecond
+	}
+
+	fmt.Fprintf(b, "%s\n-- a testbench for %s, which runs it for %s\n", vhdlHeader, mod.Circuit, duration)
+	fmt.Fprintf(b, "entity %s is\nend entity;\n\n", name)
+	fmt.Fprintf(b, "architecture sim of %s is\n", name)
+
+	fmt.Fprintf(b, "    component %s is\n", mod.Name)
+	vhdlPorts(b, mod, "        ")
+	b.WriteString("    end component;\n\n")
+
+	fmt.Fprintf(b, "    signal %s : boolean := false;\n", done)
+	for _, signal := range signals {
+		fmt.Fprintf(b, "    %s\n", signal)
+	}
+
+	b.WriteString("begin\n")
+
+	if len(assocs) > 0 {
+		fmt.Fprintf(b, "    %s : %s\n        port map (\n            %s\n        );\n", dut, mod.Name, strings.Join(assocs, ",\n            "))
+	} else {
+		fmt.Fprintf(b, "    %s : %s;\n", dut, mod.Name)
+	}
+
+	for _, clock := range clocks {
+		fmt.Fprintf(b, "\n    -- %s\n", clock.Label)
+		fmt.Fprintf(
+			b,
+			"    %s <= not %s after %s when not %s else '0';\n",
+			clock.Name, clock.Name, vhdlHalf(clock.Period), done,
+		)
+	}
+
+	fmt.Fprintf(b, "\n    %s : process\n    begin\n", stimulus)
+	fmt.Fprintf(b, "        -- set the inputs of %s here.\n", mod.Name)
+	fmt.Fprintf(b, "        wait for %s;\n", vhdlTime(duration))
+	fmt.Fprintf(b, "        %s <= true;\n", done)
+	b.WriteString("        wait;\n    end process;\nend architecture;\n")
+}
+
+func contains(ports []*Port, port *Port) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+
+	return false
+}
+
+// vhdlTime writes a duration in the largest unit it's a whole number
+// of.
+func vhdlTime(d time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Second, "sec"},
+		{time.Millisecond, "ms"},
+		{time.Microsecond, "us"},
+	}
+
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d %s", d/u.unit, u.name)
+		}
+	}
+
+	return fmt.Sprintf("%d ns", d/time.Nanosecond)
+}
+
+// vhdlHalf writes half of a clock's period, which is a whole number
+// of picoseconds even when the period is an odd number of
+// nanoseconds.
+func vhdlHalf(period time.Duration) string {
+	if period%2 != 0 {
+		return fmt.Sprintf("%d ps", int64(period/time.Nanosecond)*500)
+	}
+
+	return vhdlTime(period / 2)
+}
+
+var vhdlOps = map[netlist.Op]string{
+	netlist.And:  "and",
+	netlist.Or:   "or",
+	netlist.Xor:  "xor",
+	netlist.Nand: "nand",
+	netlist.Nor:  "nor",
+	netlist.Xnor: "xnor",
+}
+
+func vhdlExpr(e Expr) (string, error) {
+	switch ex := e.(type) {
+	case Const:
+		if ex {
+			return "'1'", nil
+		}
+
+		return "'0'", nil
+
+	case Ref:
+		return vhdlRef(ex), nil
+
+	case *Gate:
+		var args []string
+		for _, arg := range ex.Args {
+			a, err := vhdlExpr(arg)
+			if err != nil {
+				return "", err
+			}
+
+			args = append(args, a)
+		}
+
+		switch ex.Op {
+		case netlist.Not:
+			return fmt.Sprintf("(not %s)", args[0]), nil
+
+		case netlist.Imp:
+			return fmt.Sprintf("((not %s) or %s)", args[0], args[1]), nil
+		}
+
+		if op, ok := vhdlOps[ex.Op]; ok {
+			return fmt.Sprintf("(%s %s %s)", args[0], op, args[1]), nil
+		}
+
+		return "", fmt.Errorf("cannot write a gate with the operation '%s' in VHDL", ex.Op)
+	}
+
+	return "", fmt.Errorf("cannot write an expression of type %T in VHDL", e)
+}
+
+func vhdlRef(ref Ref) string {
+	if ref.Index < 0 {
+		return ref.Name
+	}
+
+	return fmt.Sprintf("%s(%d)", ref.Name, ref.Index)
+}
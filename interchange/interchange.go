@@ -0,0 +1,172 @@
+// Package interchange converts programs and netlists to and from a
+// stable, versioned JSON format, so that other tools can read and
+// write circuits without parsing or generating booleang code.
+//
+// A document holds a program, a netlist, or both:
+//
+//	{
+//	    "format": "booleang",
+//	    "version": 1,
+//	    "program": { "circuits": [ ... ] },
+//	    "netlist": { "circuit": "main", "nodes": [ ... ], ... }
+//	}
+//
+// The program is the syntax tree of the circuits, with the range of
+// source code each part was parsed from. The netlist is a circuit
+// which has been elaborated into gates, and can be simulated as it
+// is. Changes which would make an older reader misread a document
+// increase the version, and documents with a newer version than this
+// package knows about are rejected.
+package interchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/zac-garby/booleang/ast"
+	"github.com/zac-garby/booleang/netlist"
+	"github.com/zac-garby/booleang/token"
+)
+
+// Format identifies a document as a booleang document.
+const Format = "booleang"
+
+// Version is the version of the format written by this package, and
+// the newest version it can read.
+const Version = 1
+
+// A Document is the top level of a JSON file.
+type Document struct {
+	Format  string   `json:"format"`
+	Version int      `json:"version"`
+	Program *Program `json:"program,omitempty"`
+	Netlist *Netlist `json:"netlist,omitempty"`
+}
+
+// An Error is a problem with a document, at the given path through
+// it, e.g. netlist.nodes[3].args.
+type Error struct {
+	Message string
+	Path    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf(
+		"-* JSON Error @ [%s] *- %s",
+		e.Path,
+		e.Message,
+	)
+}
+
+func errorf(path, msg string, format ...interface{}) error {
+	return &Error{
+		Message: fmt.Sprintf(msg, format...),
+		Path:    path,
+	}
+}
+
+// Encode writes a document holding a program and a netlist, either
+// of which can be nil.
+func Encode(w io.Writer, prog *ast.Program, net *netlist.Netlist) error {
+	doc := &Document{
+		Format:  Format,
+		Version: Version,
+	}
+
+	if prog != nil {
+		doc.Program = NewProgram(prog)
+	}
+
+	if net != nil {
+		doc.Netlist = NewNetlist(net)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(doc)
+}
+
+// Decode reads a document, and converts its program and netlist,
+// which are nil if the document doesn't have them.
+func Decode(r io.Reader) (*ast.Program, *netlist.Netlist, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var doc Document
+	if err := dec.Decode(&doc); err != nil {
+		return nil, nil, &Error{Message: err.Error()}
+	}
+
+	if doc.Format != Format {
+		return nil, nil, errorf("format", "expected the format to be '%s', not '%s'", Format, doc.Format)
+	}
+
+	if doc.Version < 1 || doc.Version > Version {
+		return nil, nil, errorf("version", "cannot read version %d. the newest version supported is %d", doc.Version, Version)
+	}
+
+	var (
+		prog *ast.Program
+		net  *netlist.Netlist
+		err  error
+	)
+
+	if doc.Program != nil {
+		if prog, err = doc.Program.AST(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if doc.Netlist != nil {
+		if net, err = doc.Netlist.Netlist(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return prog, net, nil
+}
+
+// A Range is a range of source code. The lines and columns both start
+// at 1.
+type Range struct {
+	File  string   `json:"file,omitempty"`
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// A Position is a position in a file.
+type Position struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+// newRange converts a range, leaving it out if it's empty, which it
+// is for things which weren't parsed from any source code.
+func newRange(r token.Range) *Range {
+	if r == (token.Range{}) {
+		return nil
+	}
+
+	return &Range{
+		File:  r.Start.File,
+		Start: Position{r.Start.Line, r.Start.Col},
+		End:   Position{r.End.Line, r.End.Col},
+	}
+}
+
+func (r *Range) token() token.Range {
+	if r == nil {
+		return token.Range{}
+	}
+
+	return token.Range{
+		Start: token.Position{Line: r.Start.Line, Col: r.Start.Col, File: r.File},
+		End:   token.Position{Line: r.End.Line, Col: r.End.Col, File: r.File},
+	}
+}
+
+func (r *Range) span() ast.Span {
+	return ast.Span(r.token())
+}
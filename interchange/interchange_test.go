@@ -0,0 +1,176 @@
+package interchange_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/zac-garby/booleang/interchange"
+	"github.com/zac-garby/booleang/netlist"
+	"github.com/zac-garby/booleang/parser"
+	"github.com/zac-garby/booleang/sim"
+)
+
+const input = `
+circuit adder (a, b, cin) -> (sum, cout) {
+	((a ^ b) ^ cin) -> sum;
+	(((a ^ b) & cin) | (a & b)) -> cout;
+}
+
+circuit addN<n> (a[n], b[n]) -> (s[n], carry) {
+	0 -> carry;
+
+	for i in 0..n {
+		adder (a[i], b[i], carry) -> (s[i], carry);
+	}
+}
+
+circuit main {
+	%x (x0, x1);
+	(1, 0) -> %x;
+	s[2];
+	addN<2> (%x, 2'd3) -> (s, c);
+
+	clock 1s %t[2] {
+		(!s[0], %t) -> (s[0], t0, t1);
+		obit (s[0]);
+	}
+}
+`
+
+func TestRoundTrip(t *testing.T) {
+	prog, err := parser.New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	net, err := netlist.Elaborate(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := Encode(&b, prog, net); err != nil {
+		t.Fatal(err)
+	}
+
+	prog2, net2, err := Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prog2.String() != prog.String() {
+		t.Errorf("expected the program:\n%s\ngot:\n%s", prog, prog2)
+	}
+
+	if prog2.Circuits[0].Span != prog.Circuits[0].Span {
+		t.Errorf("expected the range %v, got %v", prog.Circuits[0].Span, prog2.Circuits[0].Span)
+	}
+
+	if net2.String() != net.String() {
+		t.Errorf("expected the netlist:\n%s\ngot:\n%s", net, net2)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	// a register which is inverted every second, and the AND of it
+	// with an input.
+	doc := `{
+		"format": "booleang",
+		"version": 1,
+		"netlist": {
+			"circuit": "main",
+			"registers": ["a", "x", "y"],
+			"nodes": [
+				{"op": "const", "value": true},
+				{"op": "read", "register": 0},
+				{"op": "read", "register": 1},
+				{"op": "not", "args": [2]},
+				{"op": "and", "args": [1, 2]}
+			],
+			"inputs": [0],
+			"outputs": [],
+			"init": [
+				{"kind": "assign", "targets": [0], "sources": [0]}
+			],
+			"clocks": [
+				{
+					"period": "1s",
+					"parent": -1,
+					"body": [
+						{"kind": "assign", "targets": [1], "sources": [3]},
+						{"kind": "assign", "targets": [2], "sources": [4]}
+					]
+				}
+			],
+			"watches": []
+		}
+	}`
+
+	prog, net, err := Decode(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prog != nil {
+		t.Errorf("expected no program, got %s", prog)
+	}
+
+	s := sim.FromNetlist(net)
+
+	for i, expected := range []bool{true, false, true} {
+		if err := s.Step(time.Second); err != nil {
+			t.Fatal(err)
+		}
+
+		if x, _ := s.Get("x"); x != expected {
+			t.Errorf("expected x to be %t after %ds, got %t", expected, i+1, x)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	cases := map[string]string{
+		`{"format": "verilog", "version": 1}`:  "format",
+		`{"format": "booleang", "version": 2}`: "version",
+
+		`{"format": "booleang", "version": 1, "netlist": {
+			"circuit": "main", "registers": [],
+			"nodes": [{"op": "not", "args": [0]}]
+		}}`: "netlist.nodes[0].args[0]",
+
+		`{"format": "booleang", "version": 1, "netlist": {
+			"circuit": "main", "registers": [],
+			"nodes": [{"op": "maybe"}]
+		}}`: "netlist.nodes[0].op",
+
+		`{"format": "booleang", "version": 1, "netlist": {
+			"circuit": "main", "registers": ["a"], "nodes": [],
+			"clocks": [{"period": "0s", "parent": -1, "body": []}]
+		}}`: "netlist.clocks[0].period",
+
+		`{"format": "booleang", "version": 1, "netlist": {
+			"circuit": "main", "registers": [], "nodes": [],
+			"watches": [{"builtin": "oprint", "label": "x", "nodes": []}]
+		}}`: "netlist.watches[0].builtin",
+
+		`{"format": "booleang", "version": 1, "program": {
+			"circuits": [{"name": "main", "statements": [{"kind": "loop"}]}]
+		}}`: "program.circuits[0].statements[0].kind",
+	}
+
+	for doc, path := range cases {
+		_, _, err := Decode(strings.NewReader(doc))
+
+		e, ok := err.(*Error)
+		if !ok {
+			t.Errorf("expected an error at %s, got %v", path, err)
+			continue
+		}
+
+		if e.Path != path {
+			t.Errorf("expected an error at %s, got %s", path, e)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package interchange
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zac-garby/booleang/builtin"
+	"github.com/zac-garby/booleang/netlist"
+)
+
+// A Netlist is a circuit elaborated into gates, in the same way as a
+// netlist.Netlist. Registers, nodes and clocks are referred to by
+// their indices in the lists they're in.
+type Netlist struct {
+	Name     string `json:"name,omitempty"`
+	Circuit  string `json:"circuit"`
+	Generics []int  `json:"generics,omitempty"`
+
+	// Registers are the names of the registers.
+	Registers []string `json:"registers"`
+	Nodes     []*Node  `json:"nodes"`
+
+	Inputs  []int `json:"inputs"`
+	Outputs []int `json:"outputs"`
+
+	Init    []*Step  `json:"init"`
+	Clocks  []*Clock `json:"clocks"`
+	Watches []*Watch `json:"watches"`
+}
+
+// A Node is a gate. Its op is one of const, read, not, and, or, xor,
+// nand, nor, xnor and imp. A const node has a value, and a read node
+// reads a register. A not node has one argument, and the others
+// apart from const and read have two, each of which must be the index
+// of an earlier node.
+type Node struct {
+	Op       string `json:"op"`
+	Args     []int  `json:"args,omitempty"`
+	Value    bool   `json:"value,omitempty"`
+	Register int    `json:"register,omitempty"`
+}
+
+// A Step is an assignment, whose kind is assign, or a call to the
+// input builtin, whose kind is input. An assignment sets each of its
+// targets to the value of the node at the same position in its
+// sources, and an input reads a value into its targets.
+type Step struct {
+	Kind    string `json:"kind"`
+	Range   *Range `json:"range,omitempty"`
+	Label   string `json:"label,omitempty"`
+	Targets []int  `json:"targets"`
+	Sources []int  `json:"sources,omitempty"`
+}
+
+// A Clock runs its body every period, which is written like a Go
+// duration, e.g. 1.5s. Its parent is the index of the clock it was
+// declared inside, which must come before it, or -1.
+type Clock struct {
+	Period string  `json:"period"`
+	Range  *Range  `json:"range,omitempty"`
+	Parent int     `json:"parent"`
+	Body   []*Step `json:"body"`
+}
+
+// A Watch is a call to an output builtin, such as obit, which shows
+// the values of some nodes. The builtin must be one of the output
+// builtins.
+type Watch struct {
+	Builtin string `json:"builtin"`
+	Label   string `json:"label"`
+	Nodes   []int  `json:"nodes"`
+}
+
+// NewNetlist converts a netlist, which must have been flattened, as
+// it is by netlist.Elaborate.
+func NewNetlist(net *netlist.Netlist) *Netlist {
+	n := &Netlist{
+		Name:      net.Name,
+		Circuit:   net.Circuit,
+		Generics:  net.Generics,
+		Registers: []string{},
+		Nodes:     []*Node{},
+		Inputs:    ints(net.Inputs),
+		Outputs:   ints(net.Outputs),
+		Init:      newSteps(net.Init),
+		Clocks:    []*Clock{},
+		Watches:   []*Watch{},
+	}
+
+	for _, reg := range net.Registers {
+		n.Registers = append(n.Registers, reg.Name)
+	}
+
+	for _, node := range net.Nodes {
+		nd := &Node{
+			Op:    node.Op.String(),
+			Args:  node.Args,
+			Value: node.Value,
+		}
+
+		if node.Op == netlist.Read {
+			nd.Register = node.Register
+		}
+
+		n.Nodes = append(n.Nodes, nd)
+	}
+
+	for _, clock := range net.Clocks {
+		n.Clocks = append(n.Clocks, &Clock{
+			Period: clock.Period.String(),
+			Range:  newRange(clock.Range),
+			Parent: clock.Parent,
+			Body:   newSteps(clock.Body),
+		})
+	}
+
+	for _, watch := range net.Watches {
+		n.Watches = append(n.Watches, &Watch{
+			Builtin: watch.Builtin,
+			Label:   watch.Label,
+			Nodes:   ints(watch.Nodes),
+		})
+	}
+
+	return n
+}
+
+func newSteps(steps []netlist.Step) []*Step {
+	ss := []*Step{}
+
+	for _, step := range steps {
+		switch st := step.(type) {
+		case *netlist.Assign:
+			ss = append(ss, &Step{
+				Kind:    "assign",
+				Targets: ints(st.Targets),
+				Sources: ints(st.Sources),
+			})
+
+		case *netlist.Input:
+			ss = append(ss, &Step{
+				Kind:    "input",
+				Range:   newRange(st.Range),
+				Label:   st.Label,
+				Targets: ints(st.Targets),
+			})
+		}
+	}
+
+	return ss
+}
+
+// ints makes sure a list of indices is written as [] rather than
+// null when it's empty.
+func ints(is []int) []int {
+	if is == nil {
+		return []int{}
+	}
+
+	return is
+}
+
+// Netlist converts a netlist back, checking that every index in it
+// refers to something which exists, so it can be simulated safely.
+func (n *Netlist) Netlist() (*netlist.Netlist, error) {
+	net := &netlist.Netlist{
+		Name:     n.Name,
+		Circuit:  n.Circuit,
+		Generics: n.Generics,
+	}
+
+	for _, name := range n.Registers {
+		net.Registers = append(net.Registers, &netlist.Register{Name: name})
+	}
+
+	regs := len(n.Registers)
+
+	ops := make(map[string]netlist.Op)
+	for op := netlist.Const; op <= netlist.Imp; op++ {
+		ops[op.String()] = op
+	}
+
+	for i, node := range n.Nodes {
+		path := fmt.Sprintf("netlist.nodes[%d]", i)
+
+		if node == nil {
+			return nil, errorf(path, "expected a node")
+		}
+
+		op, ok := ops[node.Op]
+		if !ok {
+			return nil, errorf(path+".op", "unknown op '%s'", node.Op)
+		}
+
+		args := 2
+		switch op {
+		case netlist.Const, netlist.Read:
+			args = 0
+		case netlist.Not:
+			args = 1
+		}
+
+		if len(node.Args) != args {
+			return nil, errorf(path+".args", "%s takes %d arguments, not %d", op, args, len(node.Args))
+		}
+
+		// the arguments have to come first, so the nodes can't form
+		// a loop.
+		if err := indices(path+".args", node.Args, i, "node"); err != nil {
+			return nil, err
+		}
+
+		register := -1
+		if op == netlist.Read {
+			if node.Register < 0 || node.Register >= regs {
+				return nil, errorf(path+".register", "there is no register %d", node.Register)
+			}
+
+			register = node.Register
+		}
+
+		net.Nodes = append(net.Nodes, &netlist.Node{
+			Op:       op,
+			Args:     node.Args,
+			Value:    node.Value,
+			Register: register,
+		})
+	}
+
+	nodes := len(n.Nodes)
+
+	if err := indices("netlist.inputs", n.Inputs, regs, "register"); err != nil {
+		return nil, err
+	}
+
+	if err := indices("netlist.outputs", n.Outputs, regs, "register"); err != nil {
+		return nil, err
+	}
+
+	net.Inputs, net.Outputs = n.Inputs, n.Outputs
+
+	var err error
+	if net.Init, err = steps("netlist.init", n.Init, regs, nodes); err != nil {
+		return nil, err
+	}
+
+	for i, c := range n.Clocks {
+		path := fmt.Sprintf("netlist.clocks[%d]", i)
+
+		if c == nil {
+			return nil, errorf(path, "expected a clock")
+		}
+
+		period, err := time.ParseDuration(c.Period)
+		if err != nil || period <= 0 {
+			return nil, errorf(path+".period", "invalid period '%s'", c.Period)
+		}
+
+		if c.Parent < -1 || c.Parent >= i {
+			return nil, errorf(path+".parent", "the parent of clock %d must be an earlier clock, or -1", i)
+		}
+
+		body, err := steps(path+".body", c.Body, regs, nodes)
+		if err != nil {
+			return nil, err
+		}
+
+		net.Clocks = append(net.Clocks, &netlist.Clock{
+			Period: period,
+			Range:  c.Range.token(),
+			Parent: c.Parent,
+			Body:   body,
+		})
+	}
+
+	for i, w := range n.Watches {
+		path := fmt.Sprintf("netlist.watches[%d]", i)
+
+		if w == nil {
+			return nil, errorf(path, "expected a watch")
+		}
+
+		if b, ok := builtin.Lookup(w.Builtin); !ok || b.Kind != builtin.Output {
+			return nil, errorf(path+".builtin", "'%s' isn't an output builtin", w.Builtin)
+		}
+
+		if err := indices(path+".nodes", w.Nodes, nodes, "node"); err != nil {
+			return nil, err
+		}
+
+		net.Watches = append(net.Watches, &netlist.Watch{
+			Builtin: w.Builtin,
+			Label:   w.Label,
+			Nodes:   w.Nodes,
+		})
+	}
+
+	return net, nil
+}
+
+func steps(path string, ss []*Step, regs, nodes int) ([]netlist.Step, error) {
+	var steps []netlist.Step
+
+	for i, s := range ss {
+		path := fmt.Sprintf("%s[%d]", path, i)
+
+		if s == nil {
+			return nil, errorf(path, "expected a step")
+		}
+
+		if err := indices(path+".targets", s.Targets, regs, "register"); err != nil {
+			return nil, err
+		}
+
+		switch s.Kind {
+		case "assign":
+			if len(s.Sources) != len(s.Targets) {
+				return nil, errorf(path, "%d sources can't be assigned to %d targets", len(s.Sources), len(s.Targets))
+			}
+
+			if err := indices(path+".sources", s.Sources, nodes, "node"); err != nil {
+				return nil, err
+			}
+
+			steps = append(steps, &netlist.Assign{
+				Targets: s.Targets,
+				Sources: s.Sources,
+			})
+
+		case "input":
+			steps = append(steps, &netlist.Input{
+				Label:   s.Label,
+				Range:   s.Range.token(),
+				Targets: s.Targets,
+			})
+
+		default:
+			return nil, errorf(path+".kind", "unknown kind of step '%s'", s.Kind)
+		}
+	}
+
+	return steps, nil
+}
+
+// indices checks that each of a list of indices is less than a limit.
+func indices(path string, is []int, limit int, what string) error {
+	for i, index := range is {
+		if index < 0 || index >= limit {
+			return errorf(fmt.Sprintf("%s[%d]", path, i), "there is no %s %d", what, index)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,660 @@
+package interchange
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/zac-garby/booleang/ast"
+)
+
+// A Program is a list of circuits. The circuits of every included
+// file are already in the list, so the includes are only kept as a
+// record of where they came from, and aren't loaded again.
+type Program struct {
+	Name     string     `json:"name,omitempty"`
+	Range    *Range     `json:"range,omitempty"`
+	Includes []*Include `json:"includes,omitempty"`
+	Circuits []*Circuit `json:"circuits"`
+}
+
+// An Include is a file included by a program, by name or by path.
+type Include struct {
+	Range  *Range `json:"range,omitempty"`
+	ByName bool   `json:"byName,omitempty"`
+	Value  string `json:"value"`
+}
+
+// A Circuit is the definition of a circuit.
+type Circuit struct {
+	Range      *Range       `json:"range,omitempty"`
+	Name       string       `json:"name"`
+	Generics   []string     `json:"generics,omitempty"`
+	Inputs     []*Parameter `json:"inputs"`
+	Outputs    []*Parameter `json:"outputs"`
+	Statements []*Statement `json:"statements"`
+}
+
+// A Parameter is a register, a bus, part of a bus, or a macro, in the
+// same way as an ast.Parameter.
+type Parameter struct {
+	Range   *Range `json:"range,omitempty"`
+	Name    string `json:"name"`
+	Macro   bool   `json:"macro,omitempty"`
+	Suffix  *Int   `json:"suffix,omitempty"`
+	Width   *Int   `json:"width,omitempty"`
+	Indexed bool   `json:"indexed,omitempty"`
+	High    *Int   `json:"high,omitempty"`
+	Low     *Int   `json:"low,omitempty"`
+}
+
+// A Statement is one of the statements of a circuit. Its kind is one
+// of the following, and decides which of its other fields are used:
+//
+//	macro:  name, registers
+//	bus:    name, width
+//	call:   circuit, generics, inputs, outputs
+//	pipe:   inputs, outputs
+//	for:    var, from, to, body
+//	clock:  delay, counter, counterWidth, body
+//
+// The delay of a clock is written like a Go duration, e.g. 1.5s.
+type Statement struct {
+	Kind  string `json:"kind"`
+	Range *Range `json:"range,omitempty"`
+
+	Name         string        `json:"name,omitempty"`
+	Registers    []*Parameter  `json:"registers,omitempty"`
+	Width        *Int          `json:"width,omitempty"`
+	Circuit      string        `json:"circuit,omitempty"`
+	Generics     []*Int        `json:"generics,omitempty"`
+	Inputs       []*Expression `json:"inputs,omitempty"`
+	Outputs      []*Parameter  `json:"outputs,omitempty"`
+	Var          string        `json:"var,omitempty"`
+	From         *Int          `json:"from,omitempty"`
+	To           *Int          `json:"to,omitempty"`
+	Body         []*Statement  `json:"body,omitempty"`
+	Delay        string        `json:"delay,omitempty"`
+	Counter      string        `json:"counter,omitempty"`
+	CounterWidth int           `json:"counterWidth,omitempty"`
+}
+
+// An Expression is an expression, whose kind is one of the
+// following:
+//
+//	bit:         value
+//	number:      number, width
+//	identifier:  name, suffix
+//	infix:       op, left, right
+//	prefix:      op, right
+//	reduction:   op, right
+//	macro:       name
+//	index:       name, suffix, high, low
+//	concat:      parts
+//
+// The number of a number is written in decimal, as a string, since it
+// can be any size, and its width is 0 if it's unsized.
+type Expression struct {
+	Kind  string `json:"kind"`
+	Range *Range `json:"range,omitempty"`
+
+	Value    bool          `json:"value,omitempty"`
+	Number   string        `json:"number,omitempty"`
+	Width    int           `json:"width,omitempty"`
+	Name     string        `json:"name,omitempty"`
+	Suffix   *Int          `json:"suffix,omitempty"`
+	Operator string        `json:"op,omitempty"`
+	Left     *Expression   `json:"left,omitempty"`
+	Right    *Expression   `json:"right,omitempty"`
+	High     *Int          `json:"high,omitempty"`
+	Low      *Int          `json:"low,omitempty"`
+	Parts    []*Expression `json:"parts,omitempty"`
+}
+
+// An Int is an integer expression, whose kind is one of the
+// following:
+//
+//	const:  value
+//	var:    name
+//	arith:  op, left, right
+type Int struct {
+	Kind  string `json:"kind"`
+	Range *Range `json:"range,omitempty"`
+
+	Value    int    `json:"value,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Operator string `json:"op,omitempty"`
+	Left     *Int   `json:"left,omitempty"`
+	Right    *Int   `json:"right,omitempty"`
+}
+
+// NewProgram converts a program.
+func NewProgram(prog *ast.Program) *Program {
+	p := &Program{
+		Name:     prog.Name,
+		Range:    newRange(prog.Range()),
+		Circuits: []*Circuit{},
+	}
+
+	for _, inc := range prog.Includes {
+		p.Includes = append(p.Includes, &Include{
+			Range:  newRange(inc.Range()),
+			ByName: inc.ByName,
+			Value:  inc.Value,
+		})
+	}
+
+	for _, circ := range prog.Circuits {
+		p.Circuits = append(p.Circuits, &Circuit{
+			Range:      newRange(circ.Range()),
+			Name:       circ.Name,
+			Generics:   circ.Generics,
+			Inputs:     newParameters(circ.Inputs),
+			Outputs:    newParameters(circ.Outputs),
+			Statements: newStatements(circ.Statements),
+		})
+	}
+
+	return p
+}
+
+func newParameters(params ast.Parameters) []*Parameter {
+	ps := []*Parameter{}
+
+	for _, param := range params {
+		ps = append(ps, &Parameter{
+			Range:   newRange(param.Range()),
+			Name:    param.Name,
+			Macro:   param.Macro,
+			Suffix:  newInt(param.Suffix),
+			Width:   newInt(param.Width),
+			Indexed: param.Indexed,
+			High:    newInt(param.High),
+			Low:     newInt(param.Low),
+		})
+	}
+
+	return ps
+}
+
+func newStatements(stmts []ast.Statement) []*Statement {
+	ss := []*Statement{}
+
+	for _, stmt := range stmts {
+		s := &Statement{
+			Range: newRange(stmt.Range()),
+		}
+
+		switch st := stmt.(type) {
+		case *ast.MacroStmt:
+			s.Kind = "macro"
+			s.Name = st.Name
+			s.Registers = newParameters(st.Registers)
+
+		case *ast.BusStmt:
+			s.Kind = "bus"
+			s.Name = st.Name
+			s.Width = newInt(st.Width)
+
+		case *ast.Call:
+			s.Kind = "call"
+			s.Circuit = st.Circuit
+			s.Inputs = newExpressions(st.Inputs)
+			s.Outputs = newParameters(st.Outputs)
+
+			for _, g := range st.Generics {
+				s.Generics = append(s.Generics, newInt(g))
+			}
+
+		case *ast.Pipe:
+			s.Kind = "pipe"
+			s.Inputs = newExpressions(st.Inputs)
+			s.Outputs = newParameters(st.Outputs)
+
+		case *ast.For:
+			s.Kind = "for"
+			s.Var = st.Var
+			s.From = newInt(st.From)
+			s.To = newInt(st.To)
+			s.Body = newStatements(st.Body)
+
+		case *ast.Clock:
+			s.Kind = "clock"
+			s.Delay = st.Delay.String()
+			s.Counter = st.Counter
+			s.CounterWidth = st.Width
+			s.Body = newStatements(st.Body)
+		}
+
+		ss = append(ss, s)
+	}
+
+	return ss
+}
+
+func newExpressions(exprs []ast.Expression) []*Expression {
+	es := []*Expression{}
+
+	for _, expr := range exprs {
+		es = append(es, newExpression(expr))
+	}
+
+	return es
+}
+
+func newExpression(expr ast.Expression) *Expression {
+	e := &Expression{
+		Range: newRange(expr.Range()),
+	}
+
+	switch ex := expr.(type) {
+	case *ast.Bit:
+		e.Kind = "bit"
+		e.Value = ex.Value
+
+	case *ast.Number:
+		e.Kind = "number"
+		e.Number = ex.Value.String()
+		e.Width = ex.Width
+
+	case *ast.Identifier:
+		e.Kind = "identifier"
+		e.Name = ex.Value
+		e.Suffix = newInt(ex.Suffix)
+
+	case *ast.Infix:
+		e.Kind = "infix"
+		e.Operator = ex.Operator
+		e.Left = newExpression(ex.Left)
+		e.Right = newExpression(ex.Right)
+
+	case *ast.Prefix:
+		e.Kind = "prefix"
+		e.Operator = ex.Operator
+		e.Right = newExpression(ex.Right)
+
+	case *ast.Reduction:
+		e.Kind = "reduction"
+		e.Operator = ex.Operator
+		e.Right = newExpression(ex.Right)
+
+	case *ast.MacroExpr:
+		e.Kind = "macro"
+		e.Name = ex.Name
+
+	case *ast.Index:
+		e.Kind = "index"
+		e.Name = ex.Name
+		e.Suffix = newInt(ex.Suffix)
+		e.High = newInt(ex.High)
+		e.Low = newInt(ex.Low)
+
+	case *ast.Concat:
+		e.Kind = "concat"
+		e.Parts = newExpressions(ex.Parts)
+	}
+
+	return e
+}
+
+func newInt(n ast.Int) *Int {
+	if n == nil {
+		return nil
+	}
+
+	i := &Int{
+		Range: newRange(n.Range()),
+	}
+
+	switch in := n.(type) {
+	case *ast.Const:
+		i.Kind = "const"
+		i.Value = in.Value
+
+	case *ast.Var:
+		i.Kind = "var"
+		i.Name = in.Name
+
+	case *ast.Arith:
+		i.Kind = "arith"
+		i.Operator = in.Operator
+		i.Left = newInt(in.Left)
+		i.Right = newInt(in.Right)
+	}
+
+	return i
+}
+
+// AST converts a program back into a syntax tree. It checks that the
+// program has the right shape, but not that it makes sense, which is
+// left to the checker and the elaborator, as it is for parsed code.
+func (p *Program) AST() (*ast.Program, error) {
+	prog := &ast.Program{
+		Span: p.Range.span(),
+		Name: p.Name,
+	}
+
+	for i, inc := range p.Includes {
+		if inc == nil {
+			return nil, errorf(fmt.Sprintf("program.includes[%d]", i), "expected an include")
+		}
+
+		prog.Includes = append(prog.Includes, ast.Include{
+			Span:   inc.Range.span(),
+			ByName: inc.ByName,
+			Value:  inc.Value,
+		})
+	}
+
+	for i, c := range p.Circuits {
+		path := fmt.Sprintf("program.circuits[%d]", i)
+
+		if c == nil {
+			return nil, errorf(path, "expected a circuit")
+		}
+
+		if c.Name == "" {
+			return nil, errorf(path+".name", "a circuit needs a name")
+		}
+
+		circ := &ast.Circuit{
+			Span:     c.Range.span(),
+			Name:     c.Name,
+			Generics: c.Generics,
+		}
+
+		var err error
+
+		if circ.Inputs, err = parameters(path+".inputs", c.Inputs); err != nil {
+			return nil, err
+		}
+
+		if circ.Outputs, err = parameters(path+".outputs", c.Outputs); err != nil {
+			return nil, err
+		}
+
+		if circ.Statements, err = statements(path+".statements", c.Statements); err != nil {
+			return nil, err
+		}
+
+		prog.Circuits = append(prog.Circuits, circ)
+	}
+
+	return prog, nil
+}
+
+func parameters(path string, ps []*Parameter) (ast.Parameters, error) {
+	var params ast.Parameters
+
+	for i, p := range ps {
+		path := fmt.Sprintf("%s[%d]", path, i)
+
+		if p == nil {
+			return nil, errorf(path, "expected a parameter")
+		}
+
+		if p.Name == "" {
+			return nil, errorf(path+".name", "a parameter needs a name")
+		}
+
+		if p.Indexed && (p.High == nil || p.Low == nil) {
+			return nil, errorf(path, "an indexed parameter needs a high and a low bit")
+		}
+
+		param := ast.Parameter{
+			Span:    p.Range.span(),
+			Macro:   p.Macro,
+			Name:    p.Name,
+			Indexed: p.Indexed,
+		}
+
+		var err error
+
+		if param.Suffix, err = optionalInt(path+".suffix", p.Suffix); err != nil {
+			return nil, err
+		}
+
+		if param.Width, err = optionalInt(path+".width", p.Width); err != nil {
+			return nil, err
+		}
+
+		if param.High, err = optionalInt(path+".high", p.High); err != nil {
+			return nil, err
+		}
+
+		if param.Low, err = optionalInt(path+".low", p.Low); err != nil {
+			return nil, err
+		}
+
+		params = append(params, param)
+	}
+
+	return params, nil
+}
+
+func statements(path string, ss []*Statement) ([]ast.Statement, error) {
+	var stmts []ast.Statement
+
+	for i, s := range ss {
+		stmt, err := statement(fmt.Sprintf("%s[%d]", path, i), s)
+		if err != nil {
+			return nil, err
+		}
+
+		stmts = append(stmts, stmt)
+	}
+
+	return stmts, nil
+}
+
+func statement(path string, s *Statement) (ast.Statement, error) {
+	if s == nil {
+		return nil, errorf(path, "expected a statement")
+	}
+
+	var (
+		span = s.Range.span()
+		err  error
+	)
+
+	switch s.Kind {
+	case "macro":
+		st := &ast.MacroStmt{Span: span, Name: s.Name}
+		st.Registers, err = parameters(path+".registers", s.Registers)
+		return st, err
+
+	case "bus":
+		st := &ast.BusStmt{Span: span, Name: s.Name}
+		st.Width, err = integer(path+".width", s.Width)
+		return st, err
+
+	case "call":
+		st := &ast.Call{Span: span, Circuit: s.Circuit}
+
+		for i, g := range s.Generics {
+			n, err := integer(fmt.Sprintf("%s.generics[%d]", path, i), g)
+			if err != nil {
+				return nil, err
+			}
+
+			st.Generics = append(st.Generics, n)
+		}
+
+		if st.Inputs, err = expressions(path+".inputs", s.Inputs); err != nil {
+			return nil, err
+		}
+
+		st.Outputs, err = parameters(path+".outputs", s.Outputs)
+		return st, err
+
+	case "pipe":
+		st := &ast.Pipe{Span: span}
+
+		if st.Inputs, err = expressions(path+".inputs", s.Inputs); err != nil {
+			return nil, err
+		}
+
+		st.Outputs, err = parameters(path+".outputs", s.Outputs)
+		return st, err
+
+	case "for":
+		st := &ast.For{Span: span, Var: s.Var}
+
+		if st.From, err = integer(path+".from", s.From); err != nil {
+			return nil, err
+		}
+
+		if st.To, err = integer(path+".to", s.To); err != nil {
+			return nil, err
+		}
+
+		st.Body, err = statements(path+".body", s.Body)
+		return st, err
+
+	case "clock":
+		delay, err := time.ParseDuration(s.Delay)
+		if err != nil {
+			return nil, errorf(path+".delay", "invalid delay '%s'", s.Delay)
+		}
+
+		st := &ast.Clock{
+			Span:    span,
+			Delay:   delay,
+			Counter: s.Counter,
+			Width:   s.CounterWidth,
+		}
+
+		st.Body, err = statements(path+".body", s.Body)
+		return st, err
+	}
+
+	return nil, errorf(path+".kind", "unknown kind of statement '%s'", s.Kind)
+}
+
+func expressions(path string, es []*Expression) ([]ast.Expression, error) {
+	var exprs []ast.Expression
+
+	for i, e := range es {
+		expr, err := expression(fmt.Sprintf("%s[%d]", path, i), e)
+		if err != nil {
+			return nil, err
+		}
+
+		exprs = append(exprs, expr)
+	}
+
+	return exprs, nil
+}
+
+func expression(path string, e *Expression) (ast.Expression, error) {
+	if e == nil {
+		return nil, errorf(path, "expected an expression")
+	}
+
+	var (
+		span = e.Range.span()
+		err  error
+	)
+
+	switch e.Kind {
+	case "bit":
+		return &ast.Bit{Span: span, Value: e.Value}, nil
+
+	case "number":
+		value, ok := new(big.Int).SetString(e.Number, 10)
+		if !ok {
+			return nil, errorf(path+".number", "invalid number '%s'", e.Number)
+		}
+
+		return &ast.Number{Span: span, Value: value, Width: e.Width}, nil
+
+	case "identifier":
+		ex := &ast.Identifier{Span: span, Value: e.Name}
+		ex.Suffix, err = optionalInt(path+".suffix", e.Suffix)
+		return ex, err
+
+	case "infix":
+		ex := &ast.Infix{Span: span, Operator: e.Operator}
+
+		if ex.Left, err = expression(path+".left", e.Left); err != nil {
+			return nil, err
+		}
+
+		ex.Right, err = expression(path+".right", e.Right)
+		return ex, err
+
+	case "prefix":
+		ex := &ast.Prefix{Span: span, Operator: e.Operator}
+		ex.Right, err = expression(path+".right", e.Right)
+		return ex, err
+
+	case "reduction":
+		ex := &ast.Reduction{Span: span, Operator: e.Operator}
+		ex.Right, err = expression(path+".right", e.Right)
+		return ex, err
+
+	case "macro":
+		return &ast.MacroExpr{Span: span, Name: e.Name}, nil
+
+	case "index":
+		ex := &ast.Index{Span: span, Name: e.Name}
+
+		if ex.Suffix, err = optionalInt(path+".suffix", e.Suffix); err != nil {
+			return nil, err
+		}
+
+		if ex.High, err = integer(path+".high", e.High); err != nil {
+			return nil, err
+		}
+
+		ex.Low, err = integer(path+".low", e.Low)
+		return ex, err
+
+	case "concat":
+		ex := &ast.Concat{Span: span}
+		ex.Parts, err = expressions(path+".parts", e.Parts)
+		return ex, err
+	}
+
+	return nil, errorf(path+".kind", "unknown kind of expression '%s'", e.Kind)
+}
+
+// optionalInt converts an integer which can be left out.
+func optionalInt(path string, n *Int) (ast.Int, error) {
+	if n == nil {
+		return nil, nil
+	}
+
+	return integer(path, n)
+}
+
+func integer(path string, n *Int) (ast.Int, error) {
+	if n == nil {
+		return nil, errorf(path, "expected an integer")
+	}
+
+	span := n.Range.span()
+
+	switch n.Kind {
+	case "const":
+		return &ast.Const{Span: span, Value: n.Value}, nil
+
+	case "var":
+		return &ast.Var{Span: span, Name: n.Name}, nil
+
+	case "arith":
+		left, err := integer(path+".left", n.Left)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := integer(path+".right", n.Right)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ast.Arith{Span: span, Left: left, Right: right, Operator: n.Operator}, nil
+	}
+
+	return nil, errorf(path+".kind", "unknown kind of integer '%s'", n.Kind)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/zac-garby/booleang/kmap"
+	"github.com/zac-garby/booleang/truth"
+)
+
+// kmapCommand draws the Karnaugh map of one output of a circuit:
+//
+//	bl kmap [flags] <file> <circuit>
+func kmapCommand(args []string) {
+	fs := flag.NewFlagSet("kmap", flag.ExitOnError)
+
+	var (
+		format = fs.String("format", "text", "the format of the map: text or svg")
+		output = fs.String("output", "", "the output to draw, which can be left out if there's only one")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bl kmap [flags] <file> <circuit>")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if *format != "text" && *format != "svg" {
+		fmt.Fprintf(os.Stderr, "unknown format '%s'. expected text or svg\n", *format)
+		os.Exit(2)
+	}
+
+	table, err := truth.Generate(elaborate(fs.Arg(0), fs.Arg(1)), truth.Options{
+		MaxInputs: kmap.MaxInputs,
+	})
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	name := *output
+	if name == "" {
+		if len(table.Outputs) != 1 {
+			fmt.Fprintf(os.Stderr, "%s has %d outputs, so one must be chosen with -output\n", fs.Arg(1), len(table.Outputs))
+			os.Exit(2)
+		}
+
+		name = table.Outputs[0]
+	}
+
+	m, err := kmap.New(table, name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *format == "svg" {
+		err = m.WriteSVG(os.Stdout)
+	} else {
+		err = m.WriteText(os.Stdout)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,197 @@
+// Package kmap draws Karnaugh maps of the outputs of combinational
+// circuits, with the groups of a minimal sum of products marked.
+package kmap
+
+import (
+	"fmt"
+
+	"github.com/zac-garby/booleang/minimize"
+	"github.com/zac-garby/booleang/truth"
+)
+
+// The fewest and most inputs a map can be drawn for.
+const (
+	MinInputs = 2
+	MaxInputs = 6
+)
+
+// A Map is the Karnaugh map of one output of a circuit.
+//
+// A map of up to four inputs is a single grid. With five or six, the
+// first one or two inputs choose between two or four grids of the
+// other four, which are drawn side by side. Cells in the same place
+// in neighbouring grids are adjacent.
+type Map struct {
+	Function *minimize.Function
+
+	// Grids, Rows and Cols are the indices of the inputs which
+	// select a grid, a row and a column respectively.
+	Grids, Rows, Cols []int
+
+	// Groups are the products of a minimal sum of products, each of
+	// which is a rectangle of 1s in the map.
+	Groups []minimize.Implicant
+
+	values map[uint64]bool
+}
+
+// New builds the map of the named output of a truth table, which
+// must have between MinInputs and MaxInputs inputs.
+func New(t *truth.Table, output string) (*Map, error) {
+	n := len(t.Inputs)
+	if n < MinInputs || n > MaxInputs {
+		return nil, fmt.Errorf(
+			"a karnaugh map needs between %d and %d inputs, but there are %d",
+			MinInputs, MaxInputs, n,
+		)
+	}
+
+	fns, err := minimize.Table(t)
+	if err != nil {
+		return nil, err
+	}
+
+	out := -1
+	for i, name := range t.Outputs {
+		if name == output {
+			out = i
+		}
+	}
+
+	if out < 0 {
+		return nil, fmt.Errorf("there isn't an output called '%s'", output)
+	}
+
+	m := &Map{
+		Function: fns[out],
+		Groups:   fns[out].Products,
+		values:   make(map[uint64]bool),
+	}
+
+	for _, row := range t.Rows {
+		m.values[combination(row.Inputs)] = row.Outputs[out]
+	}
+
+	var (
+		grids = n - 4
+		rows  = n / 2
+	)
+
+	if grids < 0 {
+		grids = 0
+	} else {
+		rows = 2
+	}
+
+	for i := 0; i < n; i++ {
+		switch {
+		case i < grids:
+			m.Grids = append(m.Grids, i)
+		case i < grids+rows:
+			m.Rows = append(m.Rows, i)
+		default:
+			m.Cols = append(m.Cols, i)
+		}
+	}
+
+	return m, nil
+}
+
+func combination(inputs []bool) uint64 {
+	var m uint64
+
+	for _, bit := range inputs {
+		m <<= 1
+		if bit {
+			m |= 1
+		}
+	}
+
+	return m
+}
+
+// grayCode returns the values of k inputs in the order they're drawn
+// along the side of a map, where neighbours differ by one bit.
+func grayCode(k int) []int {
+	codes := make([]int, 1<<uint(k))
+
+	for i := range codes {
+		codes[i] = i ^ (i >> 1)
+	}
+
+	return codes
+}
+
+// bits sets the bits of the given inputs in a combination to the bits
+// of code, with the first input as the most significant.
+func (m *Map) bits(inputs []int, code int) uint64 {
+	var (
+		n = len(m.Function.Inputs)
+		c uint64
+	)
+
+	for i, input := range inputs {
+		if code&(1<<uint(len(inputs)-i-1)) != 0 {
+			c |= 1 << uint(n-input-1)
+		}
+	}
+
+	return c
+}
+
+// Combination returns the combination of inputs at a cell.
+func (m *Map) Combination(grid, row, col int) uint64 {
+	return m.bits(m.Grids, grayCode(len(m.Grids))[grid]) |
+		m.bits(m.Rows, grayCode(len(m.Rows))[row]) |
+		m.bits(m.Cols, grayCode(len(m.Cols))[col])
+}
+
+// Value returns the value of the output at a cell.
+func (m *Map) Value(grid, row, col int) bool {
+	return m.values[m.Combination(grid, row, col)]
+}
+
+// Size returns the number of grids, and the number of rows and
+// columns in each one.
+func (m *Map) Size() (grids, rows, cols int) {
+	return 1 << uint(len(m.Grids)), 1 << uint(len(m.Rows)), 1 << uint(len(m.Cols))
+}
+
+// Label returns the values of some inputs at a position along a side
+// of the map, e.g. 01.
+func (m *Map) Label(inputs []int, i int) string {
+	var (
+		code = grayCode(len(inputs))[i]
+		s    []byte
+	)
+
+	for j := range inputs {
+		if code&(1<<uint(len(inputs)-j-1)) != 0 {
+			s = append(s, '1')
+		} else {
+			s = append(s, '0')
+		}
+	}
+
+	return string(s)
+}
+
+// Names returns the names of some inputs, separated by spaces.
+func (m *Map) Names(inputs []int) string {
+	var s string
+
+	for i, input := range inputs {
+		if i > 0 {
+			s += " "
+		}
+
+		s += m.Function.Inputs[input]
+	}
+
+	return s
+}
+
+// InGroup checks whether a cell is inside the ith group.
+func (m *Map) InGroup(i, grid, row, col int) bool {
+	return m.Groups[i].Covers(m.Combination(grid, row, col))
+}
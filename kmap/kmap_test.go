@@ -0,0 +1,110 @@
+package kmap_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	. "github.com/zac-garby/booleang/kmap"
+	"github.com/zac-garby/booleang/netlist"
+	"github.com/zac-garby/booleang/parser"
+	"github.com/zac-garby/booleang/truth"
+)
+
+const input = `
+circuit adder (a, b, cin) -> (sum, cout) {
+	((a ^ b) ^ cin) -> sum;
+	(((a ^ b) & cin) | (a & b)) -> cout;
+}
+
+circuit corners (a, b, c, d) -> (o) {
+	(!b & !d) -> o;
+}
+
+circuit single (a) -> (o) {
+	(!a) -> o;
+}
+`
+
+func build(t *testing.T, circuit, output string) (*Map, error) {
+	prog, err := parser.New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	net, err := netlist.ElaborateCircuit(prog, circuit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := truth.Generate(net, truth.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(table, output)
+}
+
+func TestText(t *testing.T) {
+	m, err := build(t, "adder", "cout")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := m.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `cout:
+a \ b cin
+     00      01      11      10
+  ┌───────┬───────┬───────┬───────┐
+0 │ 0     │ 0     │ 1 A   │ 0     │
+  ├───────┼───────┼───────┼───────┤
+1 │ 0     │ 1 B   │ 1 ABC │ 1 C   │
+  └───────┴───────┴───────┴───────┘
+
+groups:
+  A: (b & cin)
+  B: (a & cin)
+  C: (a & b)
+`
+
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestSVG(t *testing.T) {
+	m, err := build(t, "corners", "o")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := m.WriteSVG(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := xml.Unmarshal(buf.Bytes(), new(interface{})); err != nil {
+		t.Errorf("invalid svg: %s", err)
+	}
+
+	// the group wraps around both edges, so it's split into a
+	// rectangle in each corner.
+	if n := strings.Count(buf.String(), `rx="10"`); n != 4 {
+		t.Errorf("expected the group to be drawn as 4 rectangles, got %d", n)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := build(t, "single", "o"); err == nil {
+		t.Error("expected an error for a circuit with one input")
+	}
+
+	if _, err := build(t, "adder", "carry"); err == nil {
+		t.Error("expected an error for an output which doesn't exist")
+	}
+}
@@ -0,0 +1,170 @@
+package kmap
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// The dimensions of an SVG map, in pixels.
+const (
+	cellSize   = 48
+	gridGap    = 40
+	marginTop  = 72
+	marginLeft = 72
+	legendRow  = 22
+	padding    = 16
+	charWidth  = 9
+)
+
+// colours are the colours of the groups in an SVG map, which are
+// reused if there are more groups than colours.
+var colours = []string{
+	"#e6194b", "#3cb44b", "#4363d8", "#f58231",
+	"#911eb4", "#42d4f4", "#f032e6", "#9a6324",
+}
+
+// WriteSVG draws the map as an SVG image. Each group is drawn as a
+// coloured rectangle around its cells, or as several if it wraps
+// around the edges of the map, and is listed underneath.
+func (m *Map) WriteSVG(w io.Writer) error {
+	var (
+		b                 strings.Builder
+		grids, rows, cols = m.Size()
+		gridWidth         = cols * cellSize
+		width             = marginLeft + grids*gridWidth + (grids-1)*gridGap + padding
+		height            = marginTop + rows*cellSize + padding + legendRow*(len(m.Groups)+1)
+	)
+
+	// make sure the legend fits, assuming each character is at most
+	// charWidth pixels wide.
+	for i, group := range m.Groups {
+		label := fmt.Sprintf("%s: %s", groupName(i), m.Function.Product(group))
+		if w := 2*padding + 20 + len(label)*charWidth; w > width {
+			width = w
+		}
+	}
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="14">`+"\n", width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+
+	text := func(x, y int, anchor, s string) {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="%s" dominant-baseline="middle">%s</text>`+"\n", x, y, anchor, html.EscapeString(s))
+	}
+
+	for g := 0; g < grids; g++ {
+		left := marginLeft + g*(gridWidth+gridGap)
+
+		if grids > 1 {
+			text(left+gridWidth/2, 12, "middle", fmt.Sprintf("%s = %s", m.Names(m.Grids), m.Label(m.Grids, g)))
+		}
+
+		text(left-8, marginTop-40, "end", m.Names(m.Rows)+" \\")
+		text(left, marginTop-40, "start", m.Names(m.Cols))
+
+		for c := 0; c < cols; c++ {
+			text(left+c*cellSize+cellSize/2, marginTop-14, "middle", m.Label(m.Cols, c))
+		}
+
+		for r := 0; r < rows; r++ {
+			text(left-8, marginTop+r*cellSize+cellSize/2, "end", m.Label(m.Rows, r))
+
+			for c := 0; c < cols; c++ {
+				x, y := left+c*cellSize, marginTop+r*cellSize
+
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="black"/>`+"\n", x, y, cellSize, cellSize)
+
+				value := "0"
+				if m.Value(g, r, c) {
+					value = "1"
+				}
+
+				text(x+cellSize/2, y+cellSize/2, "middle", value)
+			}
+		}
+
+		for i := range m.Groups {
+			m.svgGroup(&b, i, g, left)
+		}
+	}
+
+	legend := marginTop + rows*cellSize + padding + legendRow
+
+	for i, group := range m.Groups {
+		y := legend + i*legendRow
+
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="12" height="12" fill="%s"/>`+"\n", padding, y-6, colour(i))
+		text(padding+20, y, "start", fmt.Sprintf("%s: %s", groupName(i), m.Function.Product(group)))
+	}
+
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func colour(i int) string {
+	return colours[i%len(colours)]
+}
+
+// svgGroup draws the part of the ith group inside a grid. A group
+// covers every combination of some rows and some columns, which
+// might not be next to each other if it wraps around, so it's drawn
+// as a rectangle for each run of neighbouring rows and columns.
+func (m *Map) svgGroup(b *strings.Builder, i, grid, left int) {
+	var (
+		_, rows, cols = m.Size()
+		inRow         = make([]bool, rows)
+		inCol         = make([]bool, cols)
+	)
+
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if m.InGroup(i, grid, r, c) {
+				inRow[r] = true
+				inCol[c] = true
+			}
+		}
+	}
+
+	// each group is inset by a different amount, so groups covering
+	// the same cells can still be told apart.
+	inset := 4 + 3*(i%4)
+
+	for _, rs := range runs(inRow) {
+		for _, cs := range runs(inCol) {
+			fmt.Fprintf(
+				b,
+				`<rect x="%d" y="%d" width="%d" height="%d" rx="10" fill="%s" fill-opacity="0.15" stroke="%s" stroke-width="2"/>`+"\n",
+				left+cs[0]*cellSize+inset,
+				marginTop+rs[0]*cellSize+inset,
+				(cs[1]-cs[0])*cellSize-2*inset,
+				(rs[1]-rs[0])*cellSize-2*inset,
+				colour(i),
+				colour(i),
+			)
+		}
+	}
+}
+
+// runs finds the runs of set elements, as pairs of the index of the
+// first one and the index after the last one.
+func runs(set []bool) [][2]int {
+	var result [][2]int
+
+	for i := 0; i < len(set); i++ {
+		if !set[i] {
+			continue
+		}
+
+		start := i
+		for i < len(set) && set[i] {
+			i++
+		}
+
+		result = append(result, [2]int{start, i})
+	}
+
+	return result
+}
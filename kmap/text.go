@@ -0,0 +1,130 @@
+package kmap
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// groupName names the ith group, e.g. A for the first one. Capital
+// letters are used so they aren't mistaken for the inputs, whose
+// names are usually lower case.
+func groupName(i int) string {
+	const names = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	if i < len(names) {
+		return names[i : i+1]
+	}
+
+	return "?"
+}
+
+// cell returns the text in a cell: its value, and the names of the
+// groups it's in.
+func (m *Map) cell(grid, row, col int) string {
+	if !m.Value(grid, row, col) {
+		return "0"
+	}
+
+	var groups string
+	for i := range m.Groups {
+		if m.InGroup(i, grid, row, col) {
+			groups += groupName(i)
+		}
+	}
+
+	if groups == "" {
+		return "1"
+	}
+
+	return "1 " + groups
+}
+
+// WriteText draws the map with box drawing characters. Each cell
+// contains its value, followed by the names of the groups it's in,
+// which are listed underneath the map.
+func (m *Map) WriteText(w io.Writer) error {
+	var (
+		b                 strings.Builder
+		grids, rows, cols = m.Size()
+		width             = len(m.Label(m.Cols, 0))
+		margin            = len(m.Rows) + 1
+	)
+
+	for g := 0; g < grids; g++ {
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				if l := len(m.cell(g, r, c)); l > width {
+					width = l
+				}
+			}
+		}
+	}
+
+	width += 2
+
+	line := func(left, middle, right string) {
+		b.WriteString(strings.Repeat(" ", margin))
+		b.WriteString(left)
+
+		for c := 0; c < cols; c++ {
+			if c > 0 {
+				b.WriteString(middle)
+			}
+
+			b.WriteString(strings.Repeat("─", width))
+		}
+
+		b.WriteString(right + "\n")
+	}
+
+	fmt.Fprintf(&b, "%s:\n", m.Function.Output)
+
+	for g := 0; g < grids; g++ {
+		if grids > 1 {
+			fmt.Fprintf(&b, "\n%s = %s\n", m.Names(m.Grids), m.Label(m.Grids, g))
+		}
+
+		fmt.Fprintf(&b, "%s \\ %s\n", m.Names(m.Rows), m.Names(m.Cols))
+
+		header := strings.Repeat(" ", margin+1)
+		for c := 0; c < cols; c++ {
+			header += centre(m.Label(m.Cols, c), width) + " "
+		}
+		b.WriteString(strings.TrimRight(header, " ") + "\n")
+
+		line("┌", "┬", "┐")
+
+		for r := 0; r < rows; r++ {
+			if r > 0 {
+				line("├", "┼", "┤")
+			}
+
+			fmt.Fprintf(&b, "%*s │", margin-1, m.Label(m.Rows, r))
+
+			for c := 0; c < cols; c++ {
+				fmt.Fprintf(&b, " %-*s│", width-1, m.cell(g, r, c))
+			}
+
+			b.WriteString("\n")
+		}
+
+		line("└", "┴", "┘")
+	}
+
+	if len(m.Groups) > 0 {
+		b.WriteString("\ngroups:\n")
+
+		for i, group := range m.Groups {
+			fmt.Fprintf(&b, "  %s: %s\n", groupName(i), m.Function.Product(group))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func centre(s string, width int) string {
+	left := (width - len(s)) / 2
+	return strings.Repeat(" ", left) + s + strings.Repeat(" ", width-len(s)-left)
+}
@@ -19,6 +19,10 @@ func New(str, file string) func() token.Token {
 		col   = 1
 		line  = 1
 		ch    = make(chan token.Token)
+
+		// prev is the type of the last token, so a sign after an
+		// operand can be read as an operator.
+		prev token.Type
 	)
 
 	go func() {
@@ -65,6 +69,17 @@ func New(str, file string) func() token.Token {
 					if len(match) > 0 {
 						found = true
 						t, literal, whole := handler(match)
+
+						// the -1 in N-1 is a subtraction, not a
+						// negative number.
+						if t == token.Number && operands[prev] && (whole[0] == '-' || whole[0] == '+') {
+							t, literal, whole = token.Minus, whole[:1], whole[:1]
+							if literal == "+" {
+								t = token.Plus
+							}
+						}
+
+						prev = t
 						l := len(whole)
 
 						ch <- token.Token{
@@ -169,6 +184,16 @@ func idTransformer(t token.Type, literal, whole string) (token.Type, string, str
 	return t, literal, whole
 }
 
+// operands are the types of token which can come before an infix
+// operator.
+var operands = map[token.Type]bool{
+	token.Number:       true,
+	token.Sized:        true,
+	token.Ident:        true,
+	token.RightParen:   true,
+	token.RightBracket: true,
+}
+
 type lexicalPair struct {
 	regex   string
 	handler handler
@@ -176,6 +201,7 @@ type lexicalPair struct {
 
 var lexemes = []lexicalPair{
 	// literals
+	{`^\d+'[bBoOdDhH][0-9a-zA-Z_]+`, h(token.Sized, 0, none)},
 	{`^[-+]?\d+(?:\.\d+)?`, h(token.Number, 0, none)},
 	{`^"((\\"|[^"])*)"`, h(token.String, 1, stringTransformer)},
 	{`^'((\\'|[^'])*)'`, h(token.String, 1, stringTransformer)},
@@ -187,10 +213,24 @@ var lexemes = []lexicalPair{
 	{`^\)`, h(token.RightParen, 0, none)},
 	{`^\{`, h(token.LeftBrace, 0, none)},
 	{`^\}`, h(token.RightBrace, 0, none)},
+	{`^\[`, h(token.LeftBracket, 0, none)},
+	{`^\]`, h(token.RightBracket, 0, none)},
 	{`^\,`, h(token.Comma, 0, none)},
 	{`^%`, h(token.Macro, 0, none)},
 	{`^->`, h(token.Arrow, 0, none)},
 	{`^:`, h(token.Colon, 0, none)},
+	{`^\.\.`, h(token.DotDot, 0, none)},
+	{`^<`, h(token.LeftAngle, 0, none)},
+	{`^>`, h(token.RightAngle, 0, none)},
+	{`^\+`, h(token.Plus, 0, none)},
+	{`^-`, h(token.Minus, 0, none)},
+	{`^\*`, h(token.Star, 0, none)},
+
+	// negated infix operators, which must be matched before the
+	// prefix operators they start with
+	{`^!&`, h(token.Infix, 0, none)},
+	{`^!\|`, h(token.Infix, 0, none)},
+	{`^!\^`, h(token.Infix, 0, none)},
 
 	// prefix operators
 	{`^!`, h(token.Prefix, 0, none)},
@@ -203,4 +243,10 @@ var lexemes = []lexicalPair{
 	{`^∧`, h(token.Infix, 0, none)},
 	{`^∨`, h(token.Infix, 0, none)},
 	{`^⊻`, h(token.Infix, 0, none)},
+	{`^⊼`, h(token.Infix, 0, none)},
+	{`^⊽`, h(token.Infix, 0, none)},
+	{`^↔`, h(token.Infix, 0, none)},
+	{`^≡`, h(token.Infix, 0, none)},
+	{`^=>`, h(token.Infix, 0, none)},
+	{`^→`, h(token.Infix, 0, none)},
 }
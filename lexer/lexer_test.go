@@ -9,7 +9,9 @@ import (
 
 func TestLexer(t *testing.T) {
 	input := `
-        5 -2 +4.33 0.1290; # some numbers
+        5 -2 +4.33 0.1290; # some numbers, with signs after them
+        (-3, +2); # signed numbers
+        4'b1001 8'hFF 8'd200 12'o7_7;
         ident_1 π___05xyz 5a;
         "hello \" world" 'foo \' bar';
 
@@ -18,23 +20,30 @@ func TestLexer(t *testing.T) {
 
         # the infixes:
         & | ^ ∧ ∨ ⊻
+        !& !| !^ ⊼ ⊽ ↔ ≡ => →
 
-        ; ( ) { } , % -> :
+        ; ( ) { } [ ] , % -> : < > + - * ..
 
-        clock name circuit include
+        clock name circuit include for
 
         $ # this token is illegal
     `
 
 	expected := []token.Type{
-		token.Number, token.Number, token.Number, token.Number, token.Semi,
+		token.Number, token.Minus, token.Number, token.Plus, token.Number, token.Number, token.Semi,
+		token.LeftParen, token.Number, token.Comma, token.Number, token.RightParen, token.Semi,
+		token.Sized, token.Sized, token.Sized, token.Sized, token.Semi,
 		token.Ident, token.Ident, token.Number, token.Ident, token.Semi,
 		token.String, token.String, token.Semi,
 		token.Prefix, token.Prefix,
 		token.Infix, token.Infix, token.Infix, token.Infix, token.Infix, token.Infix,
+		token.Infix, token.Infix, token.Infix, token.Infix, token.Infix, token.Infix,
+		token.Infix, token.Infix, token.Infix,
 		token.Semi, token.LeftParen, token.RightParen, token.LeftBrace, token.RightBrace,
+		token.LeftBracket, token.RightBracket,
 		token.Comma, token.Macro, token.Arrow, token.Colon,
-		token.Clock, token.Name, token.Circuit, token.Include,
+		token.LeftAngle, token.RightAngle, token.Plus, token.Minus, token.Star, token.DotDot,
+		token.Clock, token.Name, token.Circuit, token.Include, token.For,
 		token.Illegal,
 	}
 
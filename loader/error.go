@@ -0,0 +1,22 @@
+package loader
+
+import (
+	"fmt"
+
+	"github.com/zac-garby/booleang/token"
+)
+
+// An Error represents an error encountered while resolving the
+// includes of a program.
+type Error struct {
+	Message string
+	Range   token.Range
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf(
+		"-* Include Error @ [%s] *- %s",
+		e.Range,
+		e.Message,
+	)
+}
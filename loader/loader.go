@@ -0,0 +1,279 @@
+package loader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zac-garby/booleang/ast"
+	"github.com/zac-garby/booleang/lexer"
+	"github.com/zac-garby/booleang/parser"
+	"github.com/zac-garby/booleang/token"
+)
+
+// EnvPath is the environment variable which holds the default
+// search path, separated in the same way as the PATH variable.
+const EnvPath = "BOOLEANG_PATH"
+
+// A Loader loads a program along with every file it includes,
+// merging all of their circuits into a single program.
+//
+// A file included by path, e.g. include "adder.bl", is found
+// relative to the file including it. A file included by name,
+// e.g. include name "a cpu", is found by looking for a file with
+// the header name: "a cpu" in the including file's directory and
+// in each directory of the search path. Exactly one file must have
+// the name.
+type Loader struct {
+	// SearchPath is the list of directories searched for files
+	// included by name.
+	SearchPath []string
+
+	// Compat is passed on to the parser of each file, and any
+	// warnings it reports are collected in Warnings.
+	Compat   bool
+	Warnings parser.ErrorList
+
+	// loaded contains every file which has been loaded, and
+	// loading is the chain of files currently being loaded.
+	loaded  map[string]bool
+	loading []string
+
+	// names caches the header name of each file, by path.
+	names map[string]string
+
+	circuits map[string]*ast.Circuit
+}
+
+// New makes a Loader whose search path is read from the
+// BOOLEANG_PATH environment variable.
+func New() *Loader {
+	var path []string
+
+	for _, dir := range filepath.SplitList(os.Getenv(EnvPath)) {
+		if dir != "" {
+			path = append(path, dir)
+		}
+	}
+
+	return &Loader{
+		SearchPath: path,
+	}
+}
+
+// Load loads the program at the given path, and all of the files
+// it includes. Each file is only loaded once, even if it's included
+// more than once, and including a file which is already being
+// loaded is an error.
+func (l *Loader) Load(path string) (*ast.Program, error) {
+	l.loaded = make(map[string]bool)
+	l.loading = nil
+	l.names = make(map[string]string)
+	l.circuits = make(map[string]*ast.Circuit)
+	l.Warnings = nil
+
+	prog := &ast.Program{}
+
+	root, err := l.load(path, prog)
+	if err != nil {
+		return nil, err
+	}
+
+	prog.Span = root.Span
+	prog.Name = root.Name
+	prog.Includes = root.Includes
+
+	return prog, nil
+}
+
+// load parses a file, merges its circuits into prog, and then
+// loads each of its includes.
+func (l *Loader) load(path string, prog *ast.Program) (*ast.Program, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(string(text), path)
+	p.Compat = l.Compat
+
+	file, err := p.Parse()
+	l.Warnings = append(l.Warnings, p.Warnings...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	l.loaded[abs] = true
+	l.loading = append(l.loading, abs)
+	defer func() {
+		l.loading = l.loading[:len(l.loading)-1]
+	}()
+
+	for _, circ := range file.Circuits {
+		if prev, ok := l.circuits[circ.Name]; ok && prev.Start.File != circ.Start.File {
+			return nil, &Error{
+				Message: fmt.Sprintf(
+					"circuit '%s' is already defined at [%s]",
+					circ.Name, prev.Range(),
+				),
+				Range: circ.Range(),
+			}
+		}
+
+		l.circuits[circ.Name] = circ
+		prog.Circuits = append(prog.Circuits, circ)
+	}
+
+	for _, inc := range file.Includes {
+		incPath, err := l.resolve(path, inc)
+		if err != nil {
+			return nil, err
+		}
+
+		incAbs, err := filepath.Abs(incPath)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, loading := range l.loading {
+			if loading == incAbs {
+				return nil, &Error{
+					Message: fmt.Sprintf(
+						"include cycle: %s -> %s",
+						l.chain(l.loading[i:]),
+						incPath,
+					),
+					Range: inc.Range(),
+				}
+			}
+		}
+
+		if l.loaded[incAbs] {
+			continue
+		}
+
+		if _, err := l.load(incPath, prog); err != nil {
+			return nil, err
+		}
+	}
+
+	return file, nil
+}
+
+// resolve finds the path of the file an include refers to.
+func (l *Loader) resolve(from string, inc ast.Include) (string, error) {
+	dir := filepath.Dir(from)
+
+	if !inc.ByName {
+		path := inc.Value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			return "", &Error{
+				Message: fmt.Sprintf("cannot include '%s': %s", inc.Value, err),
+				Range:   inc.Range(),
+			}
+		}
+
+		return path, nil
+	}
+
+	var (
+		dirs    = append([]string{dir}, l.SearchPath...)
+		matches []string
+		seen    = make(map[string]bool)
+	)
+
+	// every file with the name is found, so that a name which is
+	// ambiguous is reported instead of depending on the order the
+	// files are found in.
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.bl"))
+		if err != nil {
+			continue
+		}
+
+		for _, file := range files {
+			abs, err := filepath.Abs(file)
+			if err != nil || seen[abs] {
+				continue
+			}
+
+			seen[abs] = true
+
+			if l.name(file) == inc.Value {
+				matches = append(matches, file)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", &Error{
+			Message: fmt.Sprintf(
+				"cannot include '%s': no file with that name was found in %s",
+				inc.Value, strings.Join(dirs, ", "),
+			),
+			Range: inc.Range(),
+		}
+
+	case 1:
+		return matches[0], nil
+	}
+
+	return "", &Error{
+		Message: fmt.Sprintf(
+			"cannot include '%s': more than one file has that name: %s",
+			inc.Value, strings.Join(matches, ", "),
+		),
+		Range: inc.Range(),
+	}
+}
+
+// name reads the name from the header of a file, without parsing
+// the rest of it. Files without a valid header have no name.
+func (l *Loader) name(path string) string {
+	if name, ok := l.names[path]; ok {
+		return name
+	}
+
+	var name string
+
+	if text, err := ioutil.ReadFile(path); err == nil {
+		next := lexer.New(string(text), path)
+
+		if next().Type == token.Name && next().Type == token.Colon {
+			if tok := next(); tok.Type == token.String {
+				name = tok.Literal
+			}
+		}
+	}
+
+	l.names[path] = name
+
+	return name
+}
+
+func (l *Loader) chain(paths []string) string {
+	var rel []string
+
+	for _, path := range paths {
+		if r, err := filepath.Rel(".", path); err == nil && !strings.HasPrefix(r, "..") {
+			path = r
+		}
+
+		rel = append(rel, path)
+	}
+
+	return strings.Join(rel, " -> ")
+}
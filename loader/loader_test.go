@@ -0,0 +1,125 @@
+package loader_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/zac-garby/booleang/loader"
+)
+
+// write creates each of the files in a new temporary directory.
+func write(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "booleang")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := write(t, map[string]string{
+		"main.bl": `name: "main";
+include "parts/adder.bl";
+include name "gates";
+circuit main {}`,
+
+		"parts/adder.bl": `name: "adder";
+include "../lib/gates.bl";
+circuit adder {}`,
+
+		"lib/gates.bl": `name: "gates";
+circuit nand {}`,
+	})
+	defer os.RemoveAll(dir)
+
+	l := New()
+	l.SearchPath = []string{filepath.Join(dir, "lib")}
+
+	prog, err := l.Load(filepath.Join(dir, "main.bl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prog.Name != "main" {
+		t.Errorf("expected the program to be called main, got %s", prog.Name)
+	}
+
+	var names []string
+	for _, circ := range prog.Circuits {
+		names = append(names, circ.Name)
+	}
+
+	// gates.bl is included twice, but only loaded once
+	if strings.Join(names, " ") != "main adder nand" {
+		t.Errorf("expected circuits main, adder and nand, got %v", names)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := write(t, map[string]string{
+		"cycle.bl": `name: "cycle";
+include "a.bl";`,
+
+		"a.bl": `name: "a";
+include name "cycle";`,
+
+		"missing.bl": `name: "missing";
+include "nowhere.bl";`,
+
+		"unnamed.bl": `name: "unnamed";
+include name "nobody";`,
+
+		"conflict.bl": `name: "conflict";
+include "other.bl";
+circuit foo {}`,
+
+		"other.bl": `name: "other";
+circuit foo {}`,
+
+		"ambiguous.bl": `name: "ambiguous";
+include name "twin";`,
+
+		"lib/one.bl": `name: "twin";`,
+		"lib/two.bl": `name: "twin";`,
+	})
+	defer os.RemoveAll(dir)
+
+	expected := map[string]string{
+		"cycle.bl":    "include cycle",
+		"missing.bl":  "cannot include 'nowhere.bl'",
+		"unnamed.bl":  "no file with that name",
+		"conflict.bl": "circuit 'foo' is already defined",
+		"ambiguous.bl": fmt.Sprintf(
+			"more than one file has that name: %s, %s",
+			filepath.Join(dir, "lib", "one.bl"), filepath.Join(dir, "lib", "two.bl"),
+		),
+	}
+
+	for file, exp := range expected {
+		l := New()
+		l.SearchPath = []string{dir, filepath.Join(dir, "lib")}
+
+		_, err := l.Load(filepath.Join(dir, file))
+
+		if err == nil || !strings.Contains(err.Error(), exp) {
+			t.Errorf("%s: expected an error containing %q, got %v", file, exp, err)
+		}
+	}
+}
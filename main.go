@@ -4,13 +4,32 @@ package main
 // /bl directory will generate a binary called `bl`.
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 
+	"github.com/zac-garby/booleang/ast"
+	"github.com/zac-garby/booleang/check"
+	"github.com/zac-garby/booleang/display"
+	"github.com/zac-garby/booleang/interchange"
+	"github.com/zac-garby/booleang/loader"
+	"github.com/zac-garby/booleang/netlist"
 	"github.com/zac-garby/booleang/parser"
+	"github.com/zac-garby/booleang/prompt"
+	"github.com/zac-garby/booleang/sim"
+	"github.com/zac-garby/booleang/token"
+)
+
+var (
+	virtual = flag.Bool("virtual", false, "run the simulation as fast as possible, instead of in real time")
+	limit   = flag.Duration("for", 0, "stop the simulation after this much simulated time")
+	script  = flag.String("stimulus", "", "read inputs from this file, instead of prompting for them")
+	compat  = flag.Bool("compat", false, "warn about expressions whose meaning changed when operators were given precedence")
 )
 
 func main() {
@@ -22,20 +41,28 @@ func main() {
 		quit()
 	}(c)
 
-	args := os.Args
+	flag.Parse()
+	args := flag.Args()
 
-	if len(args) < 2 {
+	if len(args) < 1 {
 		fmt.Println("no file specified...\nexecute a file by passing it's path as an argument")
 		os.Exit(1)
 	}
 
-	file, err := os.Open(args[1])
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	switch args[0] {
+	case "truth":
+		truthCommand(args[1:])
+	case "minimize":
+		minimizeCommand(args[1:])
+	case "kmap":
+		kmapCommand(args[1:])
+	case "export":
+		exportCommand(args[1:])
+	case "graph":
+		graphCommand(args[1:])
+	default:
+		handleFile(args[0])
 	}
-
-	handleFile(args[1], file)
 }
 
 func quit() {
@@ -43,21 +70,172 @@ func quit() {
 	os.Exit(0)
 }
 
-func handleFile(path string, file *os.File) {
-	text, err := ioutil.ReadAll(file)
+// load loads and checks a program, exiting if there are any errors.
+// If needMain is set, the program must have a 'main' circuit. The
+// program can be written in booleang, or in a JSON document.
+func load(path string, needMain bool) *ast.Program {
+	var prog *ast.Program
+
+	if isJSON(path) {
+		if prog, _ = decode(path); prog == nil {
+			fmt.Fprintf(os.Stderr, "%s doesn't contain a program\n", path)
+			os.Exit(1)
+		}
+	} else {
+		l := loader.New()
+		l.Compat = *compat
+
+		var err error
+		prog, err = l.Load(path)
+		report(l.Warnings)
+
+		if err != nil {
+			report(err)
+			os.Exit(1)
+		}
+	}
+
+	var errs []error
+	if needMain {
+		errs = check.Program(prog)
+	} else {
+		errs = check.Circuits(prog)
+	}
+
+	if len(errs) > 0 {
+		for _, err := range errs {
+			report(err)
+		}
+
+		os.Exit(1)
+	}
+
+	return prog
+}
+
+// isJSON reports whether a file is a JSON document, rather than
+// booleang code.
+func isJSON(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// decode reads the program and netlist from a JSON document, either
+// of which can be nil, and exits if it can't.
+func decode(path string) (*ast.Program, *netlist.Netlist) {
+	f, err := os.Open(path)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	defer f.Close()
 
-	filename := filepath.Base(path)
-
-	p := parser.New(string(text), filename)
-	prog, err := p.Parse()
+	prog, net, err := interchange.Decode(f)
 	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+		os.Exit(1)
+	}
+
+	return prog, net
+}
+
+func handleFile(path string) {
+	var net *netlist.Netlist
+
+	// a JSON document's netlist is simulated as it is, without
+	// elaborating its program.
+	if isJSON(path) {
+		_, net = decode(path)
+	}
+
+	if net == nil {
+		var err error
+
+		if net, err = netlist.Elaborate(load(path, true)); err != nil {
+			report(err)
+			os.Exit(1)
+		}
+	}
+
+	s := sim.FromNetlist(net)
+	d := display.New(os.Stdout)
+
+	s.OnStep = func(s *sim.Simulator) {
+		d.Show(s.Time(), s.Outputs())
+	}
+	s.Limit = *limit
+
+	if *virtual {
+		s.Mode = sim.Virtual
+	}
+
+	if *script != "" {
+		f, err := os.Open(*script)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+
+		s.Input = prompt.NewScript(f, filepath.Base(*script))
+	} else {
+		s.Input = &interrupter{
+			Reader:  prompt.NewTerminal(os.Stdin, os.Stdout),
+			display: d,
+		}
+	}
+
+	if err := s.Run(context.Background()); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+}
+
+// An interrupter interrupts a display after reading each input, so
+// the display doesn't draw over the prompt.
+type interrupter struct {
+	sim.Reader
+	display display.Display
+}
+
+func (i *interrupter) Read(label string, width int) ([]bool, error) {
+	defer i.display.Interrupt()
+	return i.Reader.Read(label, width)
+}
+
+// report prints an error to stderr. If the error refers to a range
+// of source code, the line it's on is printed underneath.
+func report(err error) {
+	if list, ok := err.(parser.ErrorList); ok || err == nil {
+		for _, err := range list {
+			report(err)
+		}
+
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, err)
+
+	var rng token.Range
+
+	switch e := err.(type) {
+	case *parser.Error:
+		rng = e.Range
+	case *loader.Error:
+		rng = e.Range
+	case *check.Error:
+		rng = e.Range
+	case *netlist.Error:
+		rng = e.Range
+	default:
+		return
+	}
 
-	fmt.Println(prog)
+	text, err := ioutil.ReadFile(rng.Start.File)
+	if err != nil {
+		return
+	}
+
+	if excerpt := parser.Excerpt(string(text), rng); excerpt != "" {
+		fmt.Fprintf(os.Stderr, "%s\n\n", excerpt)
+	}
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zac-garby/booleang/minimize"
+	"github.com/zac-garby/booleang/truth"
+)
+
+// minimizeCommand prints the minimal forms of each output of a
+// circuit:
+//
+//	bl minimize [flags] <file> <circuit>
+func minimizeCommand(args []string) {
+	fs := flag.NewFlagSet("minimize", flag.ExitOnError)
+
+	var (
+		source = fs.Bool("source", false, "print the minimised circuit as booleang code")
+		pos    = fs.Bool("pos", false, "with -source, write each output as a product of sums instead of a sum of products")
+		max    = fs.Int("max", truth.DefaultMaxInputs, "the most input bits a circuit can have before it's refused")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bl minimize [flags] <file> <circuit>")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	table, err := truth.Generate(elaborate(fs.Arg(0), fs.Arg(1)), truth.Options{
+		MaxInputs: *max,
+	})
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fns, err := minimize.Table(table)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *source {
+		form := minimize.SumOfProducts
+		if *pos {
+			form = minimize.ProductOfSums
+		}
+
+		// a generic circuit's parameters are already substituted into
+		// the widths of its buses, so its name is written without them.
+		name := fs.Arg(1)
+		if i := strings.IndexByte(name, '<'); i >= 0 {
+			name = name[:i]
+		}
+
+		if err := minimize.WriteSource(os.Stdout, name, fns, form); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	for _, fn := range fns {
+		fmt.Printf("%s:\n", fn.Output)
+		fmt.Printf("  sum of products: %s\n", fn.SumOfProducts())
+		fmt.Printf("  product of sums: %s\n", fn.ProductOfSums())
+	}
+}
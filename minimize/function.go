@@ -0,0 +1,209 @@
+// Package minimize finds the minimal sum of products and product of
+// sums forms of the outputs of a combinational circuit.
+package minimize
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/zac-garby/booleang/ast"
+	"github.com/zac-garby/booleang/truth"
+)
+
+// A Function is the minimised form of one output of a circuit.
+type Function struct {
+	Inputs []string
+	Output string
+
+	// Products are the terms of a minimal sum of products, which is
+	// true whenever one of them is.
+	Products []Implicant
+
+	// Sums are the terms of a minimal product of sums. Each one is an
+	// implicant of the output's complement, so it's false whenever
+	// the inputs it depends on have the given values.
+	Sums []Implicant
+}
+
+// Table minimises every output of a truth table. The table mustn't
+// have been sampled, since every row is needed.
+func Table(t *truth.Table) ([]*Function, error) {
+	if t.Sampled {
+		return nil, errors.New("can't minimise a sampled truth table")
+	}
+
+	n := len(t.Inputs)
+	if n > 64 {
+		return nil, fmt.Errorf("can't minimise a function of %d inputs. the limit is 64", n)
+	}
+
+	var fns []*Function
+
+	for out, name := range t.Outputs {
+		var ones, zeros []uint64
+
+		for _, row := range t.Rows {
+			m := combination(row.Inputs)
+
+			if row.Outputs[out] {
+				ones = append(ones, m)
+			} else {
+				zeros = append(zeros, m)
+			}
+		}
+
+		fns = append(fns, &Function{
+			Inputs:   t.Inputs,
+			Output:   name,
+			Products: Minimize(n, ones, nil),
+			Sums:     Minimize(n, zeros, nil),
+		})
+	}
+
+	return fns, nil
+}
+
+func combination(inputs []bool) uint64 {
+	var m uint64
+
+	for _, bit := range inputs {
+		m <<= 1
+		if bit {
+			m |= 1
+		}
+	}
+
+	return m
+}
+
+// SumOfProducts returns the function as an OR of ANDs.
+func (f *Function) SumOfProducts() ast.Expression {
+	if len(f.Products) == 0 {
+		return number(0)
+	}
+
+	var terms []ast.Expression
+
+	for _, imp := range f.Products {
+		terms = append(terms, f.Product(imp))
+	}
+
+	return join(terms, "|", number(0))
+}
+
+// ProductOfSums returns the function as an AND of ORs.
+func (f *Function) ProductOfSums() ast.Expression {
+	if len(f.Sums) == 0 {
+		return number(1)
+	}
+
+	var terms []ast.Expression
+
+	for _, imp := range f.Sums {
+		terms = append(terms, f.term(imp, false, "|"))
+	}
+
+	return join(terms, "&", number(1))
+}
+
+// Product returns one of the function's products as an AND of its
+// literals.
+func (f *Function) Product(imp Implicant) ast.Expression {
+	return f.term(imp, true, "&")
+}
+
+// term joins the literals of an implicant with an operator. If
+// positive is set, an input which must be high is written as it is,
+// and otherwise it's negated.
+func (f *Function) term(imp Implicant, positive bool, op string) ast.Expression {
+	var (
+		n        = len(f.Inputs)
+		literals []ast.Expression
+	)
+
+	for i, name := range f.Inputs {
+		bit := uint64(1) << uint(n-i-1)
+		if imp.Mask&bit != 0 {
+			continue
+		}
+
+		lit := register(name)
+		if (imp.Value&bit != 0) != positive {
+			lit = &ast.Prefix{
+				Right:    lit,
+				Operator: "!",
+			}
+		}
+
+		literals = append(literals, lit)
+	}
+
+	// an empty AND is always high, and an empty OR is always low.
+	empty := number(0)
+	if op == "&" {
+		empty = number(1)
+	}
+
+	return join(literals, op, empty)
+}
+
+// join combines some expressions with an operator, or returns empty
+// if there aren't any.
+func join(exprs []ast.Expression, op string, empty ast.Expression) ast.Expression {
+	if len(exprs) == 0 {
+		return empty
+	}
+
+	result := exprs[0]
+
+	for _, e := range exprs[1:] {
+		result = &ast.Infix{
+			Left:     result,
+			Right:    e,
+			Operator: op,
+		}
+	}
+
+	return result
+}
+
+func number(n int64) ast.Expression {
+	return &ast.Number{
+		Value: big.NewInt(n),
+	}
+}
+
+// register makes an expression which refers to the register with the
+// given name, which is an index if it's a bit of a bus, e.g. a[3].
+func register(name string) ast.Expression {
+	if bus, i, ok := busBit(name); ok {
+		return &ast.Index{
+			Name: bus,
+			High: &ast.Const{Value: i},
+			Low:  &ast.Const{Value: i},
+		}
+	}
+
+	return &ast.Identifier{
+		Value: name,
+	}
+}
+
+// busBit splits a register's name into the name of its bus and its
+// index, e.g. a[3] into a and 3.
+func busBit(name string) (string, int, bool) {
+	open := strings.IndexByte(name, '[')
+	if open < 0 || !strings.HasSuffix(name, "]") {
+		return "", 0, false
+	}
+
+	i, err := strconv.Atoi(name[open+1 : len(name)-1])
+	if err != nil {
+		return "", 0, false
+	}
+
+	return name[:open], i, true
+}
@@ -0,0 +1,120 @@
+package minimize_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	. "github.com/zac-garby/booleang/minimize"
+	"github.com/zac-garby/booleang/netlist"
+	"github.com/zac-garby/booleang/parser"
+	"github.com/zac-garby/booleang/truth"
+)
+
+func TestMinimize(t *testing.T) {
+	tests := []struct {
+		n               int
+		ones, dontCares []uint64
+		terms, literals int
+	}{
+		{2, nil, nil, 0, 0},
+		{2, []uint64{0, 1, 2, 3}, nil, 1, 0},
+		{3, []uint64{1, 2, 4, 7}, nil, 4, 12},
+		{3, []uint64{3, 5, 6, 7}, nil, 3, 6},
+		{4, []uint64{4, 8, 10, 11, 12, 15}, []uint64{9, 14}, 3, 7},
+		{3, []uint64{0, 1, 2, 5, 6, 7}, nil, 3, 6},
+	}
+
+	for _, test := range tests {
+		imps := Minimize(test.n, test.ones, test.dontCares)
+
+		literals := 0
+		for _, imp := range imps {
+			literals += imp.Literals(test.n)
+		}
+
+		if len(imps) != test.terms || literals != test.literals {
+			t.Errorf(
+				"%v: expected %d terms with %d literals, got %v",
+				test.ones, test.terms, test.literals, imps,
+			)
+		}
+
+		for m := uint64(0); m < 1<<uint(test.n); m++ {
+			covered := false
+			for _, imp := range imps {
+				covered = covered || imp.Covers(m)
+			}
+
+			if contains(test.ones, m) != covered && !contains(test.dontCares, m) {
+				t.Errorf("%v: %d is wrongly covered", test.ones, m)
+			}
+		}
+	}
+}
+
+func contains(ms []uint64, m uint64) bool {
+	for _, x := range ms {
+		if x == m {
+			return true
+		}
+	}
+
+	return false
+}
+
+const input = `
+circuit adder (a, b, cin) -> (sum, cout) {
+	(((a & b) | (a & b & cin)) ^ (a & cin) ^ (b & cin) ^ (a & b & cin)) -> cout;
+	(a ^ b ^ cin) -> sum;
+}
+
+circuit pick (a[2], s) -> (o[2], always, never) {
+	((a & s) | (a & !s)) -> o;
+	(s | !s) -> always;
+	(s & !s) -> never;
+}
+`
+
+// TestSource checks that both forms of a circuit's source have the
+// same truth table as the circuit itself.
+func TestSource(t *testing.T) {
+	for _, name := range []string{"adder", "pick"} {
+		original := table(t, input, name)
+
+		fns, err := Table(original)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, form := range []Form{SumOfProducts, ProductOfSums} {
+			var buf bytes.Buffer
+			if err := WriteSource(&buf, name, fns, form); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := table(t, buf.String(), name); !reflect.DeepEqual(got, original) {
+				t.Errorf("the minimised form of %s is wrong:\n%s", name, buf.String())
+			}
+		}
+	}
+}
+
+func table(t *testing.T, source, circuit string) *truth.Table {
+	prog, err := parser.New(source, "test").Parse()
+	if err != nil {
+		t.Fatalf("%s\n%s", err, source)
+	}
+
+	net, err := netlist.ElaborateCircuit(prog, circuit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := truth.Generate(net, truth.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return table
+}
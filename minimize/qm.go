@@ -0,0 +1,227 @@
+package minimize
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// An Implicant is a product of literals, such as a & !c, which is
+// true for every combination of inputs it matches. Each input is one
+// bit of a combination, with the first input as the most significant
+// bit, in the same order as the rows of a truth table.
+type Implicant struct {
+	// Value holds the value each input must have, for the inputs
+	// the implicant depends on. The other bits are always 0.
+	Value uint64
+
+	// Mask has a bit set for each input the implicant doesn't
+	// depend on.
+	Mask uint64
+}
+
+// Covers checks whether the implicant is true for the combination
+// of inputs m.
+func (i Implicant) Covers(m uint64) bool {
+	return m&^i.Mask == i.Value
+}
+
+// Literals returns the number of inputs, out of n, that the
+// implicant depends on.
+func (i Implicant) Literals(n int) int {
+	return n - bits.OnesCount64(i.Mask)
+}
+
+// Minimize finds a minimal sum of products for a function of n
+// inputs, using the Quine-McCluskey algorithm. The function is true
+// for each combination in ones, and can be either true or false for
+// each combination in dontCares.
+//
+// The result has as few terms as possible and, out of those, as few
+// literals as possible. For a large function, the search for the
+// cheapest cover can give up early, and return the best one it has
+// found so far.
+func Minimize(n int, ones, dontCares []uint64) []Implicant {
+	if len(ones) == 0 {
+		return nil
+	}
+
+	terms := append(append([]uint64{}, ones...), dontCares...)
+
+	return cover(n, Primes(n, terms), ones)
+}
+
+// Primes finds the prime implicants of a function of n inputs which
+// is true for each combination in terms. An implicant is prime if it
+// can't be widened to cover more combinations.
+func Primes(n int, terms []uint64) []Implicant {
+	var (
+		current = make(map[Implicant]bool)
+		primes  []Implicant
+	)
+
+	for _, t := range terms {
+		current[Implicant{Value: t}] = true
+	}
+
+	// each round combines pairs of implicants which differ in just
+	// one input, so the implicants from the last round which weren't
+	// combined with anything are prime.
+	for len(current) > 0 {
+		next := make(map[Implicant]bool)
+
+		for imp := range current {
+			combined := false
+
+			for b := 0; b < n; b++ {
+				bit := uint64(1) << uint(b)
+				if imp.Mask&bit != 0 {
+					continue
+				}
+
+				if !current[Implicant{Value: imp.Value ^ bit, Mask: imp.Mask}] {
+					continue
+				}
+
+				combined = true
+				next[Implicant{Value: imp.Value &^ bit, Mask: imp.Mask | bit}] = true
+			}
+
+			if !combined {
+				primes = append(primes, imp)
+			}
+		}
+
+		current = next
+	}
+
+	sortImplicants(primes)
+
+	return primes
+}
+
+// searchLimit is how many partial covers are considered before the
+// search for the cheapest one gives up.
+const searchLimit = 100000
+
+// cover picks the cheapest set of primes which covers every
+// combination in ones. Essential primes, which are the only ones to
+// cover some combination, are always picked, and the rest of the
+// combinations are covered by a branch and bound search.
+func cover(n int, primes []Implicant, ones []uint64) []Implicant {
+	covering := make([][]int, len(ones))
+
+	for i, m := range ones {
+		for j, p := range primes {
+			if p.Covers(m) {
+				covering[i] = append(covering[i], j)
+			}
+		}
+	}
+
+	s := &search{
+		n:        n,
+		primes:   primes,
+		ones:     ones,
+		covering: covering,
+		covered:  make([]int, len(ones)),
+	}
+
+	var chosen []int
+
+	for i := range ones {
+		if len(covering[i]) == 1 && s.covered[i] == 0 {
+			chosen = append(chosen, covering[i][0])
+			s.choose(covering[i][0], 1)
+		}
+	}
+
+	s.find(chosen)
+
+	var result []Implicant
+	for _, j := range s.best {
+		result = append(result, primes[j])
+	}
+
+	sortImplicants(result)
+
+	return result
+}
+
+type search struct {
+	n        int
+	primes   []Implicant
+	ones     []uint64
+	covering [][]int
+
+	// covered counts how many of the chosen primes cover each
+	// combination in ones.
+	covered []int
+
+	best     []int
+	bestCost int
+	tries    int
+}
+
+// cost is the cost of a set of primes. Fewer terms is always
+// better, and fewer literals breaks ties.
+func (s *search) cost(chosen []int) int {
+	cost := len(chosen) * (s.n + 1)
+
+	for _, j := range chosen {
+		cost += s.primes[j].Literals(s.n)
+	}
+
+	return cost
+}
+
+// choose adds delta to the count of each combination covered by
+// the jth prime.
+func (s *search) choose(j, delta int) {
+	for i, m := range s.ones {
+		if s.primes[j].Covers(m) {
+			s.covered[i] += delta
+		}
+	}
+}
+
+func (s *search) find(chosen []int) {
+	s.tries++
+
+	cost := s.cost(chosen)
+	if s.best != nil && (cost >= s.bestCost || s.tries > searchLimit) {
+		return
+	}
+
+	// branch on the uncovered combination with the fewest primes
+	// covering it, to keep the search narrow.
+	next := -1
+	for i := range s.ones {
+		if s.covered[i] == 0 && (next < 0 || len(s.covering[i]) < len(s.covering[next])) {
+			next = i
+		}
+	}
+
+	if next < 0 {
+		s.best = append([]int{}, chosen...)
+		s.bestCost = cost
+		return
+	}
+
+	for _, j := range s.covering[next] {
+		s.choose(j, 1)
+		s.find(append(chosen, j))
+		s.choose(j, -1)
+	}
+}
+
+// sortImplicants sorts implicants by their values, so they're in
+// the same order as the rows of a truth table.
+func sortImplicants(imps []Implicant) {
+	sort.Slice(imps, func(i, j int) bool {
+		if imps[i].Value != imps[j].Value {
+			return imps[i].Value < imps[j].Value
+		}
+
+		return imps[i].Mask < imps[j].Mask
+	})
+}
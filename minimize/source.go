@@ -0,0 +1,117 @@
+package minimize
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/zac-garby/booleang/ast"
+)
+
+// A Form is one of the two forms a function can be written in.
+type Form int
+
+// The forms a function can be written in.
+const (
+	SumOfProducts Form = iota
+	ProductOfSums
+)
+
+// Expr returns the function written in the given form.
+func (f *Function) Expr(form Form) ast.Expression {
+	if form == ProductOfSums {
+		return f.ProductOfSums()
+	}
+
+	return f.SumOfProducts()
+}
+
+// WriteSource writes the functions as a booleang circuit with the
+// given name, which has a pipe for each output. The functions must
+// all have the same inputs, like the ones returned by Table.
+func WriteSource(w io.Writer, name string, fns []*Function, form Form) error {
+	if len(fns) == 0 {
+		return fmt.Errorf("circuit '%s' doesn't have any outputs", name)
+	}
+
+	var outputs []string
+	for _, fn := range fns {
+		outputs = append(outputs, fn.Output)
+	}
+
+	if _, err := fmt.Fprintf(
+		w,
+		"circuit %s (%s) -> (%s) {\n",
+		name,
+		signature(fns[0].Inputs),
+		signature(outputs),
+	); err != nil {
+		return err
+	}
+
+	for _, fn := range fns {
+		e := fn.Expr(form)
+		expr := e.String()
+
+		// a statement starting with an identifier is a call, so a
+		// single register has to be bracketed to be piped.
+		switch e.(type) {
+		case *ast.Identifier, *ast.Index:
+			expr = "(" + expr + ")"
+		}
+
+		if _, err := fmt.Fprintf(w, "\t%s -> %s;\n", expr, param(fn.Output)); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// signature groups the registers of a circuit's parameters back into
+// buses, so a[0] and a[1] become a[2].
+func signature(names []string) ast.Parameters {
+	var (
+		params ast.Parameters
+		widths = make(map[string]*ast.Const)
+	)
+
+	for _, name := range names {
+		bus, i, ok := busBit(name)
+		if !ok {
+			params = append(params, ast.Parameter{Name: name})
+			continue
+		}
+
+		if width, ok := widths[bus]; ok {
+			if i+1 > width.Value {
+				width.Value = i + 1
+			}
+
+			continue
+		}
+
+		widths[bus] = &ast.Const{Value: i + 1}
+		params = append(params, ast.Parameter{
+			Name:  bus,
+			Width: widths[bus],
+		})
+	}
+
+	return params
+}
+
+// param makes a parameter which writes to the register with the
+// given name.
+func param(name string) ast.Parameter {
+	if bus, i, ok := busBit(name); ok {
+		return ast.Parameter{
+			Name:    bus,
+			Indexed: true,
+			High:    &ast.Const{Value: i},
+			Low:     &ast.Const{Value: i},
+		}
+	}
+
+	return ast.Parameter{Name: name}
+}
@@ -0,0 +1,1184 @@
+package netlist
+
+import (
+	"fmt"
+
+	"github.com/zac-garby/booleang/ast"
+	"github.com/zac-garby/booleang/builtin"
+	"github.com/zac-garby/booleang/token"
+)
+
+// Main is the name of the circuit a program starts at.
+const Main = "main"
+
+// CounterWidth is the width of a clock's counter, if the clock
+// doesn't specify one.
+const CounterWidth = 8
+
+// Elaborate builds a netlist from a program, starting at its
+// 'main' circuit.
+func Elaborate(prog *ast.Program) (*Netlist, error) {
+	return ElaborateCircuit(prog, Main)
+}
+
+// ElaborateCircuit builds a netlist from a program, starting at
+// the circuit with the given name. The circuit's parameters are
+// bound to fresh registers, which are listed in the netlist's
+// Inputs and Outputs. If the circuit is generic, the values of its
+// generic parameters must be given too.
+func ElaborateCircuit(prog *ast.Program, name string, generics ...int) (*Netlist, error) {
+	top, err := lookup(prog, name, generics)
+	if err != nil {
+		return nil, err
+	}
+
+	return newElaborator(prog, nil).circuit(top, generics)
+}
+
+// ElaborateModules builds a netlist for the circuit with the given
+// name, and for each circuit it calls, without inlining any of them.
+// Instead, each call is an Instance step, which refers to one of the
+// other netlists. A generic circuit has a netlist for each set of
+// values its generic parameters are given. Every netlist comes after
+// the netlists of the circuits it calls, so the last one is the
+// circuit with the given name.
+func ElaborateModules(prog *ast.Program, name string, generics ...int) ([]*Netlist, error) {
+	top, err := lookup(prog, name, generics)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &modules{
+		built: make(map[string]*Netlist),
+	}
+
+	net, err := newElaborator(prog, m).circuit(top, generics)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(m.order, net), nil
+}
+
+// lookup finds the circuit with the given name, and checks that it's
+// given the right number of generic parameters.
+func lookup(prog *ast.Program, name string, generics []int) (*ast.Circuit, error) {
+	for _, circ := range prog.Circuits {
+		if circ.Name != name {
+			continue
+		}
+
+		if len(generics) != len(circ.Generics) {
+			return nil, &Error{
+				Message: fmt.Sprintf(
+					"circuit '%s' takes %d generic parameters, but %d were given",
+					name, len(circ.Generics), len(generics),
+				),
+				Range: circ.Range(),
+			}
+		}
+
+		return circ, nil
+	}
+
+	return nil, &Error{
+		Message: fmt.Sprintf("circuit '%s' is not defined", name),
+		Range:   prog.Range(),
+	}
+}
+
+// modules holds the netlists built by ElaborateModules.
+type modules struct {
+	order []*Netlist
+
+	// built maps the name of each module to its netlist.
+	built map[string]*Netlist
+}
+
+func newElaborator(prog *ast.Program, mods *modules) *elaborator {
+	e := &elaborator{
+		prog:      prog,
+		circuits:  make(map[string]*ast.Circuit),
+		nodes:     make(map[nodeKey]int),
+		instances: make(map[string]int),
+		modules:   mods,
+	}
+
+	for _, circ := range prog.Circuits {
+		e.circuits[circ.Name] = circ
+	}
+
+	return e
+}
+
+// circuit builds the netlist of a circuit, with the given values of
+// its generic parameters.
+func (e *elaborator) circuit(top *ast.Circuit, generics []int) (*Netlist, error) {
+	e.net = &Netlist{
+		Name:     e.prog.Name,
+		Circuit:  top.Name,
+		Generics: generics,
+	}
+
+	s := e.newScope(top, "", &e.net.Init, -1)
+
+	for i, name := range top.Generics {
+		s.ints[name] = generics[i]
+	}
+
+	for _, param := range top.Inputs {
+		regs, err := s.declare(param)
+		if err != nil {
+			return nil, err
+		}
+
+		e.net.Inputs = append(e.net.Inputs, regs...)
+	}
+
+	for _, param := range top.Outputs {
+		regs, err := s.declare(param)
+		if err != nil {
+			return nil, err
+		}
+
+		e.net.Outputs = append(e.net.Outputs, regs...)
+	}
+
+	e.stack = append(e.stack, top.Name)
+
+	if err := s.statements(top.Statements); err != nil {
+		return nil, err
+	}
+
+	return e.net, nil
+}
+
+type nodeKey struct {
+	op       Op
+	a, b     int
+	value    bool
+	register int
+}
+
+type elaborator struct {
+	prog      *ast.Program
+	net       *Netlist
+	circuits  map[string]*ast.Circuit
+	nodes     map[nodeKey]int
+	instances map[string]int
+	stack     []string
+
+	// modules is nil unless calls are being built as instances.
+	modules *modules
+}
+
+// A scope holds the registers and macros visible inside a single
+// instance of a circuit.
+type scope struct {
+	*elaborator
+
+	circuit   *ast.Circuit
+	prefix    string
+	registers map[string]int
+	macros    map[string][]int
+
+	// buses maps the name of each bus to its width.
+	buses map[string]int
+
+	// ints maps the name of each of the circuit's generic
+	// parameters to its value in this instance, and the name of
+	// each loop variable to its value in the current iteration.
+	ints map[string]int
+
+	// steps is the block which new steps are appended to, and
+	// clock is the index of the clock it belongs to, or -1 for
+	// the init block.
+	steps *[]Step
+	clock int
+}
+
+func (e *elaborator) newScope(circ *ast.Circuit, prefix string, steps *[]Step, clock int) *scope {
+	return &scope{
+		elaborator: e,
+		circuit:    circ,
+		prefix:     prefix,
+		registers:  make(map[string]int),
+		macros:     make(map[string][]int),
+		buses:      make(map[string]int),
+		ints:       make(map[string]int),
+		steps:      steps,
+		clock:      clock,
+	}
+}
+
+// node adds a node to the netlist, unless an identical node
+// already exists, in which case the existing one is reused.
+func (e *elaborator) node(op Op, value bool, register int, args ...int) int {
+	key := nodeKey{op: op, a: -1, b: -1, value: value, register: register}
+
+	if len(args) > 0 {
+		key.a = args[0]
+	}
+
+	if len(args) > 1 {
+		key.b = args[1]
+	}
+
+	if id, ok := e.nodes[key]; ok {
+		return id
+	}
+
+	e.net.Nodes = append(e.net.Nodes, &Node{
+		Op:       op,
+		Args:     args,
+		Value:    value,
+		Register: register,
+	})
+
+	id := len(e.net.Nodes) - 1
+	e.nodes[key] = id
+
+	return id
+}
+
+// register finds the register with the given name in the scope,
+// creating it if it doesn't exist yet.
+func (s *scope) register(name string) int {
+	if id, ok := s.registers[name]; ok {
+		return id
+	}
+
+	s.net.Registers = append(s.net.Registers, &Register{
+		Name: s.prefix + name,
+	})
+
+	id := len(s.net.Registers) - 1
+	s.registers[name] = id
+
+	return id
+}
+
+// declare creates the registers for a parameter in a circuit's
+// signature, declaring it as a bus if it has a width.
+func (s *scope) declare(param ast.Parameter) ([]int, error) {
+	if param.Width == nil {
+		return []int{s.register(param.Name)}, nil
+	}
+
+	width, err := s.width(param.Width)
+	if err != nil {
+		return nil, err
+	}
+
+	s.buses[param.Name] = width
+
+	return s.bus(param.Name), nil
+}
+
+// name resolves the name of a register or bus, appending the value
+// of its suffix if it has one, so c<i+1> is c3 when i is 2.
+func (s *scope) name(name string, suffix ast.Int) (string, error) {
+	if suffix == nil {
+		return name, nil
+	}
+
+	n, err := s.integer(suffix)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s%d", name, n), nil
+}
+
+// width evaluates the width of a bus, which must be positive.
+func (s *scope) width(n ast.Int) (int, error) {
+	width, err := s.integer(n)
+	if err != nil {
+		return 0, err
+	}
+
+	if width < 1 {
+		return 0, s.err(n.Range(), "the width of a bus must be positive, but %s is %d", n, width)
+	}
+
+	return width, nil
+}
+
+// integer evaluates an integer expression, using the values of the
+// generic parameters of the scope's circuit and of any loop
+// variables.
+func (s *scope) integer(n ast.Int) (int, error) {
+	switch in := n.(type) {
+	case *ast.Const:
+		return in.Value, nil
+
+	case *ast.Var:
+		value, ok := s.ints[in.Name]
+		if !ok {
+			return 0, s.err(in.Range(), "'%s' is not a generic parameter or loop variable in '%s'", in.Name, s.circuit.Name)
+		}
+
+		return value, nil
+
+	case *ast.Arith:
+		left, err := s.integer(in.Left)
+		if err != nil {
+			return 0, err
+		}
+
+		right, err := s.integer(in.Right)
+		if err != nil {
+			return 0, err
+		}
+
+		switch in.Operator {
+		case "+":
+			return left + right, nil
+		case "-":
+			return left - right, nil
+		case "*":
+			return left * right, nil
+		}
+
+		return 0, s.err(in.Range(), "unknown operator: %s", in.Operator)
+	}
+
+	return 0, s.err(n.Range(), "unknown integer type: %T", n)
+}
+
+// bus returns the registers of a bus, least significant bit first.
+// If there's no bus with the given name, it returns the single
+// register with that name instead.
+func (s *scope) bus(name string) []int {
+	width, ok := s.buses[name]
+	if !ok {
+		return []int{s.register(name)}
+	}
+
+	regs := make([]int, width)
+	for i := range regs {
+		regs[i] = s.register(fmt.Sprintf("%s[%d]", name, i))
+	}
+
+	return regs
+}
+
+// slice returns the registers holding the bits high down to low
+// of a bus, with the bit at low first.
+func (s *scope) slice(name string, hi, lo ast.Int, r token.Range) ([]int, error) {
+	width, ok := s.buses[name]
+	if !ok {
+		return nil, s.err(r, "'%s' is not a bus, so it can't be indexed", name)
+	}
+
+	high, err := s.integer(hi)
+	if err != nil {
+		return nil, err
+	}
+
+	low, err := s.integer(lo)
+	if err != nil {
+		return nil, err
+	}
+
+	if high < 0 || low < 0 || high >= width || low >= width {
+		return nil, s.err(r, "%s is out of range for the %d-bit bus '%s'", indexString(name, high, low), width, name)
+	}
+
+	var (
+		bits = s.bus(name)
+		regs []int
+		step = 1
+	)
+
+	if high < low {
+		step = -1
+	}
+
+	for i := low; ; i += step {
+		regs = append(regs, bits[i])
+
+		if i == high {
+			break
+		}
+	}
+
+	return regs, nil
+}
+
+func (s *scope) emit(step Step) {
+	*s.steps = append(*s.steps, step)
+}
+
+func (s *scope) statements(stmts []ast.Statement) error {
+	for _, stmt := range stmts {
+		if err := s.statement(stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *scope) statement(stmt ast.Statement) error {
+	switch st := stmt.(type) {
+	case *ast.MacroStmt:
+		regs, err := s.params(st.Registers)
+		if err != nil {
+			return err
+		}
+
+		s.macros[st.Name] = regs
+
+	case *ast.BusStmt:
+		if _, ok := s.buses[st.Name]; ok {
+			return s.err(st.Range(), "bus '%s' is already declared", st.Name)
+		}
+
+		width, err := s.width(st.Width)
+		if err != nil {
+			return err
+		}
+
+		s.buses[st.Name] = width
+
+	case *ast.Pipe:
+		return s.pipe(st)
+
+	case *ast.Call:
+		return s.call(st)
+
+	case *ast.Clock:
+		return s.clockStmt(st)
+
+	case *ast.For:
+		return s.forStmt(st)
+
+	default:
+		return s.err(stmt.Range(), "unknown statement type: %T", stmt)
+	}
+
+	return nil
+}
+
+func (s *scope) pipe(p *ast.Pipe) error {
+	dsts, err := s.params(p.Outputs)
+	if err != nil {
+		return err
+	}
+
+	// a single unsized number is as wide as the registers it's
+	// piped into.
+	var srcs []int
+
+	if len(p.Inputs) == 1 {
+		srcs, err = s.sized(p.Inputs[0], len(dsts))
+	} else {
+		srcs, err = s.exprs(p.Inputs)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(srcs) != len(dsts) {
+		return s.err(
+			p.Range(),
+			"cannot pipe %d bits into %d registers: (%s) -> (%s)",
+			len(srcs), len(dsts), exprString(p.Inputs), p.Outputs,
+		)
+	}
+
+	s.emit(&Assign{
+		Targets: dsts,
+		Sources: srcs,
+	})
+
+	return nil
+}
+
+func (s *scope) call(c *ast.Call) error {
+	circ, ok := s.circuits[c.Circuit]
+	if !ok {
+		if b, ok := builtin.Lookup(c.Circuit); ok {
+			return s.builtin(b, c)
+		}
+
+		return s.err(c.Range(), "circuit '%s' is not defined", c.Circuit)
+	}
+
+	for _, name := range s.stack {
+		if name == circ.Name {
+			return s.err(c.Range(), "circuit '%s' instantiates itself recursively", circ.Name)
+		}
+	}
+
+	args, err := s.exprs(c.Inputs)
+	if err != nil {
+		return err
+	}
+
+	outs, err := s.params(c.Outputs)
+	if err != nil {
+		return err
+	}
+
+	if len(c.Generics) != len(circ.Generics) {
+		return s.err(
+			c.Range(),
+			"circuit '%s' takes %d generic parameters, but %d were given",
+			circ.Name, len(circ.Generics), len(c.Generics),
+		)
+	}
+
+	if s.modules != nil {
+		return s.instance(circ, c, args, outs)
+	}
+
+	instance := s.prefix + circ.Name
+	prefix := fmt.Sprintf("%s#%d.", instance, s.instances[instance])
+
+	inner := s.newScope(circ, prefix, s.steps, s.clock)
+
+	for i, name := range circ.Generics {
+		value, err := s.integer(c.Generics[i])
+		if err != nil {
+			return err
+		}
+
+		inner.ints[name] = value
+	}
+
+	ins, err := inner.widths(circ.Inputs)
+	if err != nil {
+		return err
+	}
+
+	if len(args) != ins {
+		return s.err(
+			c.Range(),
+			"circuit '%s' takes %d inputs, but %d were given",
+			circ.Name, ins, len(args),
+		)
+	}
+
+	if n, err := inner.widths(circ.Outputs); err != nil {
+		return err
+	} else if len(outs) != n {
+		return s.err(
+			c.Range(),
+			"circuit '%s' has %d outputs, but %d were given",
+			circ.Name, n, len(outs),
+		)
+	}
+
+	s.instances[instance]++
+
+	// outputs are bound straight to the caller's registers, while
+	// inputs are copied into registers local to the instance, so
+	// the callee can't modify the caller's state through them.
+	for _, param := range circ.Outputs {
+		if param.Width == nil {
+			inner.registers[param.Name] = outs[0]
+			outs = outs[1:]
+			continue
+		}
+
+		width, _ := inner.width(param.Width)
+		inner.buses[param.Name] = width
+
+		for i := 0; i < width; i++ {
+			inner.registers[fmt.Sprintf("%s[%d]", param.Name, i)] = outs[i]
+		}
+
+		outs = outs[width:]
+	}
+
+	if len(args) > 0 {
+		var targets []int
+
+		for _, param := range circ.Inputs {
+			regs, _ := inner.declare(param)
+			targets = append(targets, regs...)
+		}
+
+		s.emit(&Assign{
+			Targets: targets,
+			Sources: args,
+		})
+	}
+
+	s.stack = append(s.stack, circ.Name)
+	defer func() {
+		s.stack = s.stack[:len(s.stack)-1]
+	}()
+
+	return inner.statements(circ.Statements)
+}
+
+// instance elaborates a call as an Instance step, building the
+// netlist of the circuit it calls if it hasn't been built yet.
+func (s *scope) instance(circ *ast.Circuit, c *ast.Call, args, outs []int) error {
+	generics := make([]int, len(c.Generics))
+
+	for i, g := range c.Generics {
+		value, err := s.integer(g)
+		if err != nil {
+			return err
+		}
+
+		generics[i] = value
+	}
+
+	name := ModuleName(circ.Name, generics)
+
+	net, ok := s.modules.built[name]
+	if !ok {
+		// the new elaborator starts with the callers on its stack,
+		// so a circuit which calls itself indirectly is still found.
+		e := newElaborator(s.prog, s.modules)
+		e.stack = append([]string{}, s.stack...)
+
+		var err error
+		if net, err = e.circuit(circ, generics); err != nil {
+			return err
+		}
+
+		s.modules.built[name] = net
+		s.modules.order = append(s.modules.order, net)
+	}
+
+	if len(args) != len(net.Inputs) {
+		return s.err(
+			c.Range(),
+			"circuit '%s' takes %d inputs, but %d were given",
+			circ.Name, len(net.Inputs), len(args),
+		)
+	}
+
+	if len(outs) != len(net.Outputs) {
+		return s.err(
+			c.Range(),
+			"circuit '%s' has %d outputs, but %d were given",
+			circ.Name, len(net.Outputs), len(outs),
+		)
+	}
+
+	s.emit(&Instance{
+		Module:  name,
+		Range:   c.Range(),
+		Inputs:  args,
+		Outputs: outs,
+	})
+
+	return nil
+}
+
+// builtin elaborates a call to a builtin. Circuits defined in the
+// program take precedence over builtins with the same name.
+func (s *scope) builtin(b *builtin.Builtin, c *ast.Call) error {
+	args, err := s.exprs(c.Inputs)
+	if err != nil {
+		return err
+	}
+
+	switch b.Kind {
+	case builtin.Output:
+		if len(c.Outputs) > 0 {
+			return s.err(c.Range(), "the builtin '%s' doesn't have any outputs", b.Name)
+		}
+
+		s.net.Watches = append(s.net.Watches, &Watch{
+			Builtin: b.Name,
+			Label:   s.prefix + exprString(c.Inputs),
+			Nodes:   args,
+		})
+
+	case builtin.Input:
+		if len(args) > 0 {
+			return s.err(c.Range(), "the builtin '%s' doesn't take any inputs", b.Name)
+		}
+
+		targets, err := s.params(c.Outputs)
+		if err != nil {
+			return err
+		}
+
+		if len(targets) == 0 {
+			return s.err(c.Range(), "the builtin '%s' needs at least one output", b.Name)
+		}
+
+		s.emit(&Input{
+			Range:   c.Range(),
+			Label:   s.prefix + c.Outputs.String(),
+			Targets: targets,
+		})
+	}
+
+	return nil
+}
+
+// forStmt elaborates the body of a for loop once for each value of
+// its variable.
+func (s *scope) forStmt(f *ast.For) error {
+	from, err := s.integer(f.From)
+	if err != nil {
+		return err
+	}
+
+	to, err := s.integer(f.To)
+	if err != nil {
+		return err
+	}
+
+	// like a clock, the body shares the registers of the scope it's
+	// in, but its macros and buses aren't visible outside of it.
+	body := *s
+	body.macros = make(map[string][]int, len(s.macros))
+	body.ints = make(map[string]int, len(s.ints)+1)
+
+	for name, regs := range s.macros {
+		body.macros[name] = regs
+	}
+
+	for name, value := range s.ints {
+		body.ints[name] = value
+	}
+
+	for i := from; i < to; i++ {
+		body.ints[f.Var] = i
+
+		// each iteration declares the body's buses again.
+		body.buses = make(map[string]int, len(s.buses))
+		for name, width := range s.buses {
+			body.buses[name] = width
+		}
+
+		if err := body.statements(f.Body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *scope) clockStmt(c *ast.Clock) error {
+	s.net.Clocks = append(s.net.Clocks, &Clock{
+		Range:  c.Range(),
+		Period: c.Delay,
+		Parent: s.clock,
+	})
+
+	index := len(s.net.Clocks) - 1
+	clock := s.net.Clocks[index]
+
+	// the body shares the registers of the scope it's declared
+	// in, but its steps go into the clock instead of the enclosing
+	// block, and its macros and buses aren't visible outside of it.
+	body := *s
+	body.steps = &clock.Body
+	body.clock = index
+	body.macros = make(map[string][]int, len(s.macros))
+	body.buses = make(map[string]int, len(s.buses))
+
+	for name, regs := range s.macros {
+		body.macros[name] = regs
+	}
+
+	for name, width := range s.buses {
+		body.buses[name] = width
+	}
+
+	var counter []int
+
+	if c.Counter != "" {
+		width := c.Width
+		if width == 0 {
+			width = CounterWidth
+		}
+
+		// the registers are named after the clock, so each clock has
+		// its own counter, which can't be the same as the bits of a
+		// bus with the same name.
+		for i := 0; i < width; i++ {
+			counter = append(counter, body.register(fmt.Sprintf("clock#%d.%%%s[%d]", index, c.Counter, i)))
+		}
+
+		body.macros[c.Counter] = counter
+	}
+
+	if err := body.statements(c.Body); err != nil {
+		return err
+	}
+
+	if counter != nil {
+		body.increment(counter)
+	}
+
+	return nil
+}
+
+// increment adds one to the number stored in the given registers,
+// least significant bit first, wrapping around when it overflows.
+func (s *scope) increment(regs []int) {
+	var (
+		carry   = s.node(Const, true, -1)
+		sources []int
+	)
+
+	for _, reg := range regs {
+		bit := s.node(Read, false, reg)
+		sources = append(sources, s.node(Xor, false, -1, bit, carry))
+		carry = s.node(And, false, -1, bit, carry)
+	}
+
+	s.emit(&Assign{
+		Targets: regs,
+		Sources: sources,
+	})
+}
+
+// params resolves a list of parameters to the registers they
+// refer to, expanding any macros.
+func (s *scope) params(params ast.Parameters) ([]int, error) {
+	var regs []int
+
+	for _, param := range params {
+		if param.Macro {
+			macro, ok := s.macros[param.Name]
+			if !ok {
+				return nil, s.err(param.Range(), "macro '%%%s' is not defined", param.Name)
+			}
+
+			regs = append(regs, macro...)
+			continue
+		}
+
+		name, err := s.name(param.Name, param.Suffix)
+		if err != nil {
+			return nil, err
+		}
+
+		if !param.Indexed {
+			regs = append(regs, s.bus(name)...)
+			continue
+		}
+
+		bits, err := s.slice(name, param.High, param.Low, param.Range())
+		if err != nil {
+			return nil, err
+		}
+
+		regs = append(regs, bits...)
+	}
+
+	return regs, nil
+}
+
+// exprs builds the nodes for a list of expressions, expanding any
+// macros and buses into the values of their registers.
+func (s *scope) exprs(exprs []ast.Expression) ([]int, error) {
+	var nodes []int
+
+	for _, expr := range exprs {
+		bits, err := s.bits(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, bits...)
+	}
+
+	return nodes, nil
+}
+
+// bits builds the nodes for each bit of an expression, least
+// significant first.
+func (s *scope) bits(expr ast.Expression) ([]int, error) {
+	var regs []int
+
+	switch ex := expr.(type) {
+	case *ast.Bit:
+		return []int{s.node(Const, ex.Value, -1)}, nil
+
+	case *ast.Number:
+		if ex.Width == 0 {
+			return s.number(ex, ex.MinWidth()), nil
+		}
+
+		return s.number(ex, ex.Width), nil
+
+	case *ast.MacroExpr:
+		macro, ok := s.macros[ex.Name]
+		if !ok {
+			return nil, s.err(ex.Range(), "macro '%%%s' is not defined", ex.Name)
+		}
+
+		regs = macro
+
+	case *ast.Identifier:
+		name, err := s.name(ex.Value, ex.Suffix)
+		if err != nil {
+			return nil, err
+		}
+
+		regs = s.bus(name)
+
+	case *ast.Index:
+		name, err := s.name(ex.Name, ex.Suffix)
+		if err != nil {
+			return nil, err
+		}
+
+		slice, err := s.slice(name, ex.High, ex.Low, ex.Range())
+		if err != nil {
+			return nil, err
+		}
+
+		regs = slice
+
+	case *ast.Concat:
+		return s.exprs(ex.Parts)
+
+	case *ast.Prefix:
+		right, err := s.bits(ex.Right)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes := make([]int, len(right))
+		for i, bit := range right {
+			nodes[i] = s.node(Not, false, -1, bit)
+		}
+
+		return nodes, nil
+
+	case *ast.Infix:
+		return s.infix(ex)
+
+	case *ast.Reduction:
+		return s.reduction(ex)
+
+	case nil:
+		return nil, s.err(s.circuit.Range(), "missing expression")
+
+	default:
+		return nil, s.err(expr.Range(), "unknown expression type: %T", expr)
+	}
+
+	nodes := make([]int, len(regs))
+	for i, reg := range regs {
+		nodes[i] = s.node(Read, false, reg)
+	}
+
+	return nodes, nil
+}
+
+var infixOps = map[string]Op{
+	"&":  And,
+	"∧":  And,
+	"|":  Or,
+	"∨":  Or,
+	"^":  Xor,
+	"⊻":  Xor,
+	"!&": Nand,
+	"⊼":  Nand,
+	"!|": Nor,
+	"⊽":  Nor,
+	"!^": Xnor,
+	"↔":  Xnor,
+	"≡":  Xnor,
+	"=>": Imp,
+	"→":  Imp,
+}
+
+// infix builds the nodes for an infix expression. The operator is
+// applied to each pair of bits from its operands, which must be the
+// same width unless one of them is a single bit, in which case that
+// bit is used with every bit of the other operand.
+func (s *scope) infix(ex *ast.Infix) ([]int, error) {
+	op, ok := infixOps[ex.Operator]
+	if !ok {
+		return nil, s.err(ex.Range(), "unknown operator: %s", ex.Operator)
+	}
+
+	var (
+		left, right []int
+		err         error
+	)
+
+	// an unsized number takes the width of the other operand.
+	if unsized(ex.Left) {
+		if right, err = s.bits(ex.Right); err != nil {
+			return nil, err
+		}
+
+		if left, err = s.sized(ex.Left, len(right)); err != nil {
+			return nil, err
+		}
+	} else {
+		if left, err = s.bits(ex.Left); err != nil {
+			return nil, err
+		}
+
+		if right, err = s.sized(ex.Right, len(left)); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case len(left) == 1 && len(right) > 1:
+		left = repeat(left[0], len(right))
+	case len(right) == 1 && len(left) > 1:
+		right = repeat(right[0], len(left))
+	case len(left) != len(right):
+		return nil, s.err(
+			ex.Range(),
+			"cannot apply %s to %d bits and %d bits: %s",
+			ex.Operator, len(left), len(right), ex,
+		)
+	}
+
+	nodes := make([]int, len(left))
+	for i := range left {
+		nodes[i] = s.node(op, false, -1, left[i], right[i])
+	}
+
+	return nodes, nil
+}
+
+// reductions maps each operator which can be used as a reduction
+// to the operation used to combine the bits, and whether the result
+// is inverted, so !&%a is the same as !(&%a).
+var reductions = map[string]struct {
+	op     Op
+	invert bool
+}{
+	"&":  {And, false},
+	"∧":  {And, false},
+	"|":  {Or, false},
+	"∨":  {Or, false},
+	"^":  {Xor, false},
+	"⊻":  {Xor, false},
+	"!&": {And, true},
+	"⊼":  {And, true},
+	"!|": {Or, true},
+	"⊽":  {Or, true},
+	"!^": {Xor, true},
+	"↔":  {Xor, true},
+	"≡":  {Xor, true},
+}
+
+// reduction builds the node for a reduction, combining the bits of
+// its operand from the least significant up.
+func (s *scope) reduction(ex *ast.Reduction) ([]int, error) {
+	red, ok := reductions[ex.Operator]
+	if !ok {
+		return nil, s.err(ex.Range(), "%s can't be used as a reduction", ex.Operator)
+	}
+
+	right, err := s.bits(ex.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(right) == 0 {
+		return nil, s.err(ex.Range(), "cannot reduce an empty expression: %s", ex)
+	}
+
+	node := right[0]
+	for _, bit := range right[1:] {
+		node = s.node(red.op, false, -1, node, bit)
+	}
+
+	if red.invert {
+		node = s.node(Not, false, -1, node)
+	}
+
+	return []int{node}, nil
+}
+
+// sized builds the nodes for an expression, giving it the width
+// of its context if it's an unsized number.
+func (s *scope) sized(expr ast.Expression, width int) ([]int, error) {
+	if !unsized(expr) {
+		return s.bits(expr)
+	}
+
+	n := expr.(*ast.Number)
+	if !n.Fits(width) {
+		return nil, s.err(n.Range(), "%s doesn't fit in %d bits", n, width)
+	}
+
+	return s.number(n, width), nil
+}
+
+// number builds the constant nodes for a number with the given
+// width.
+func (s *scope) number(n *ast.Number, width int) []int {
+	var nodes []int
+
+	for _, bit := range n.Bits(width) {
+		nodes = append(nodes, s.node(Const, bit, -1))
+	}
+
+	return nodes
+}
+
+func unsized(expr ast.Expression) bool {
+	n, ok := expr.(*ast.Number)
+	return ok && n.Width == 0
+}
+
+func repeat(node, n int) []int {
+	nodes := make([]int, n)
+	for i := range nodes {
+		nodes[i] = node
+	}
+
+	return nodes
+}
+
+// widths returns the total width of the parameters in a circuit's
+// signature.
+func (s *scope) widths(params ast.Parameters) (int, error) {
+	total := 0
+
+	for _, param := range params {
+		if param.Width == nil {
+			total++
+			continue
+		}
+
+		width, err := s.width(param.Width)
+		if err != nil {
+			return 0, err
+		}
+
+		total += width
+	}
+
+	return total, nil
+}
+
+func indexString(name string, high, low int) string {
+	if high == low {
+		return fmt.Sprintf("%s[%d]", name, high)
+	}
+
+	return fmt.Sprintf("%s[%d:%d]", name, high, low)
+}
+
+func exprString(exprs []ast.Expression) string {
+	var str string
+
+	for i, expr := range exprs {
+		if i > 0 {
+			str += ", "
+		}
+
+		str += expr.String()
+	}
+
+	return str
+}
@@ -0,0 +1,29 @@
+package netlist
+
+import (
+	"fmt"
+
+	"github.com/zac-garby/booleang/token"
+)
+
+// An Error represents an error encountered while elaborating a
+// program into a netlist.
+type Error struct {
+	Message string
+	Range   token.Range
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf(
+		"-* Elaboration Error @ [%s] *- %s",
+		e.Range,
+		e.Message,
+	)
+}
+
+func (s *scope) err(r token.Range, msg string, format ...interface{}) error {
+	return &Error{
+		Message: fmt.Sprintf(msg, format...),
+		Range:   r,
+	}
+}
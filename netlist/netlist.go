@@ -0,0 +1,353 @@
+package netlist
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zac-garby/booleang/token"
+)
+
+// An Op specifies the operation performed by a Node.
+type Op int
+
+// The set of operations a Node can perform.
+const (
+	// Const is a constant bit, stored in the node's Value.
+	Const Op = iota
+
+	// Read is the current value of the node's Register.
+	Read
+
+	Not
+	And
+	Or
+	Xor
+	Nand
+	Nor
+	Xnor
+
+	// Imp is material implication: a => b is only low when a is
+	// high and b is low.
+	Imp
+)
+
+var opNames = map[Op]string{
+	Const: "const",
+	Read:  "read",
+	Not:   "not",
+	And:   "and",
+	Or:    "or",
+	Xor:   "xor",
+	Nand:  "nand",
+	Nor:   "nor",
+	Xnor:  "xnor",
+	Imp:   "imp",
+}
+
+// Symbols maps each binary operation to the operator which
+// represents it in booleang code.
+var Symbols = map[Op]string{
+	And:  "&",
+	Or:   "|",
+	Xor:  "^",
+	Nand: "!&",
+	Nor:  "!|",
+	Xnor: "!^",
+	Imp:  "=>",
+}
+
+func (o Op) String() string {
+	if name, ok := opNames[o]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("op(%d)", int(o))
+}
+
+// A Node is a single gate in a netlist. The nodes of a netlist
+// form a directed acyclic graph whose leaves are either constants
+// or register reads.
+type Node struct {
+	Op Op
+
+	// Args are the indices of the nodes this node takes as its
+	// inputs. A Not node has one argument, Const and Read nodes
+	// have none, and every other node has two.
+	Args []int
+
+	// Value is the value of a Const node.
+	Value bool
+
+	// Register is the index of the register a Read node reads.
+	Register int
+}
+
+// A Register holds a single bit of state. Registers are named
+// after the circuit instance they belong to, so a register `sum`
+// inside the second `adder` instantiated by `main` is called
+// `adder#1.sum`. Registers in the top circuit keep their own names.
+type Register struct {
+	Name string
+}
+
+// A Step is something which happens when a block of statements
+// is executed.
+type Step interface {
+	step()
+}
+
+// An Assign step evaluates all of its sources and then writes
+// them into its targets. Since every source is evaluated before
+// any target is written, (a, b) -> (b, a) swaps two registers.
+type Assign struct {
+	// Targets are register indices.
+	Targets []int
+
+	// Sources are node indices, one for each target.
+	Sources []int
+}
+
+func (a *Assign) step() {}
+
+// An Input step pauses execution until a value has been read into
+// its targets, from whatever input source the simulation is using.
+type Input struct {
+	// Label describes what is being read, e.g. "%na".
+	Label string
+
+	// Range is the range of the call to the input builtin.
+	Range token.Range
+
+	// Targets are register indices, least significant bit first.
+	Targets []int
+}
+
+func (i *Input) step() {}
+
+// An Instance step runs a call to another circuit which hasn't been
+// inlined, because the netlist was built by ElaborateModules. The
+// circuit is elaborated separately, into the netlist whose Module
+// is the same as the instance's.
+type Instance struct {
+	Module string
+
+	// Range is the range of the call.
+	Range token.Range
+
+	// Inputs are node indices, one for each of the bits of the
+	// circuit's inputs.
+	Inputs []int
+
+	// Outputs are register indices, one for each of the bits of the
+	// circuit's outputs.
+	Outputs []int
+}
+
+func (i *Instance) step() {}
+
+// A Clock is a block of steps executed at a regular interval.
+type Clock struct {
+	Period time.Duration
+
+	// Range is the range of the clock statement.
+	Range token.Range
+
+	// Parent is the index of the clock this clock was declared
+	// inside of, or -1 if it wasn't declared inside a clock.
+	Parent int
+
+	Body []Step
+}
+
+// A Watch is a call to an output builtin, which persistently
+// displays the values of some nodes.
+type Watch struct {
+	// Builtin is the name of the output builtin.
+	Builtin string
+
+	// Label describes what is being watched, e.g. "%sum".
+	Label string
+
+	Nodes []int
+}
+
+// A Netlist is a flattened, gate-level representation of a program.
+// Every call to a user-defined circuit has been inlined, and every
+// macro has been expanded into the registers it names.
+type Netlist struct {
+	// Name is the name of the program the netlist was built from.
+	Name string
+
+	// Circuit is the name of the circuit the netlist was built from,
+	// and Generics are the values of its generic parameters.
+	Circuit  string
+	Generics []int
+
+	Nodes     []*Node
+	Registers []*Register
+
+	// Inputs and Outputs are the registers bound to the parameters
+	// of the top circuit.
+	Inputs, Outputs []int
+
+	// Init contains the steps which are executed once, when the
+	// circuit starts.
+	Init []Step
+
+	Clocks []*Clock
+
+	Watches []*Watch
+}
+
+// Module is the name of the netlist's circuit, followed by the
+// values of its generic parameters, if it has any, e.g. addN<4>.
+func (n *Netlist) Module() string {
+	return ModuleName(n.Circuit, n.Generics)
+}
+
+// ModuleName names a circuit with the given values of its generic
+// parameters.
+func ModuleName(circuit string, generics []int) string {
+	if len(generics) == 0 {
+		return circuit
+	}
+
+	var args []string
+	for _, g := range generics {
+		args = append(args, fmt.Sprint(g))
+	}
+
+	return fmt.Sprintf("%s<%s>", circuit, strings.Join(args, ", "))
+}
+
+// Register finds the index of the register with the given name,
+// or returns -1 if there isn't one.
+func (n *Netlist) Register(name string) int {
+	for i, reg := range n.Registers {
+		if reg.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Expr renders the node at the given index as an expression.
+func (n *Netlist) Expr(id int) string {
+	node := n.Nodes[id]
+
+	switch node.Op {
+	case Const:
+		if node.Value {
+			return "1"
+		}
+		return "0"
+
+	case Read:
+		return n.Registers[node.Register].Name
+
+	case Not:
+		return "!" + n.Expr(node.Args[0])
+	}
+
+	return fmt.Sprintf(
+		"(%s %s %s)",
+		n.Expr(node.Args[0]),
+		Symbols[node.Op],
+		n.Expr(node.Args[1]),
+	)
+}
+
+func (n *Netlist) String() string {
+	var lines []string
+
+	lines = append(lines, fmt.Sprintf(
+		"netlist %s (%s): %d registers, %d nodes",
+		n.Circuit,
+		n.Name,
+		len(n.Registers),
+		len(n.Nodes),
+	))
+
+	lines = append(lines, "init:")
+	lines = append(lines, n.steps(n.Init)...)
+
+	for i, clock := range n.Clocks {
+		if clock.Parent >= 0 {
+			lines = append(lines, fmt.Sprintf("clock %d, every %s in clock %d:", i, clock.Period, clock.Parent))
+		} else {
+			lines = append(lines, fmt.Sprintf("clock %d, every %s:", i, clock.Period))
+		}
+
+		lines = append(lines, n.steps(clock.Body)...)
+	}
+
+	for _, watch := range n.Watches {
+		var exprs []string
+
+		for _, node := range watch.Nodes {
+			exprs = append(exprs, n.Expr(node))
+		}
+
+		lines = append(lines, fmt.Sprintf(
+			"watch %s %s: (%s)",
+			watch.Builtin,
+			watch.Label,
+			strings.Join(exprs, ", "),
+		))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (n *Netlist) steps(steps []Step) []string {
+	var lines []string
+
+	for _, step := range steps {
+		switch s := step.(type) {
+		case *Assign:
+			var srcs, dsts []string
+
+			for i := range s.Targets {
+				srcs = append(srcs, n.Expr(s.Sources[i]))
+				dsts = append(dsts, n.Registers[s.Targets[i]].Name)
+			}
+
+			lines = append(lines, fmt.Sprintf(
+				"    (%s) -> (%s)",
+				strings.Join(srcs, ", "),
+				strings.Join(dsts, ", "),
+			))
+
+		case *Input:
+			var dsts []string
+
+			for _, reg := range s.Targets {
+				dsts = append(dsts, n.Registers[reg].Name)
+			}
+
+			lines = append(lines, fmt.Sprintf("    input -> (%s)", strings.Join(dsts, ", ")))
+
+		case *Instance:
+			var srcs, dsts []string
+
+			for _, node := range s.Inputs {
+				srcs = append(srcs, n.Expr(node))
+			}
+
+			for _, reg := range s.Outputs {
+				dsts = append(dsts, n.Registers[reg].Name)
+			}
+
+			lines = append(lines, fmt.Sprintf(
+				"    %s (%s) -> (%s)",
+				s.Module,
+				strings.Join(srcs, ", "),
+				strings.Join(dsts, ", "),
+			))
+		}
+	}
+
+	return lines
+}
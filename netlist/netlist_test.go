@@ -0,0 +1,83 @@
+package netlist_test
+
+import (
+	"testing"
+
+	. "github.com/zac-garby/booleang/netlist"
+	"github.com/zac-garby/booleang/parser"
+)
+
+func TestElaborate(t *testing.T) {
+	input := `
+name: "test";
+
+circuit adder (a, b, cin) -> (sum, cout) {
+	((a ^ b) ^ cin) -> sum;
+	(((a ^ b) & cin) | (a & b)) -> cout;
+}
+
+circuit main {
+	%in (x, y);
+	(1, 0) -> %in;
+	adder (%in, 1) -> (s, c);
+	adder (s, c, 0) -> (t, d);
+
+	clock 1s {
+		!x -> x;
+	}
+}
+`
+
+	prog, err := parser.New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	net, err := Elaborate(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"x", "y", "s", "c", "t", "d", "adder#0.a", "adder#1.cin"} {
+		if net.Register(name) < 0 {
+			t.Errorf("expected a register called %s", name)
+		}
+	}
+
+	if net.Register("adder#0.sum") >= 0 {
+		t.Errorf("output parameters should be bound to the caller's registers")
+	}
+
+	if len(net.Clocks) != 1 || len(net.Clocks[0].Body) != 1 {
+		t.Errorf("expected one clock with one step")
+	}
+
+	// one step for the macro pipe, and two for each adder: one to
+	// copy its inputs and one for each of its pipes
+	if len(net.Init) != 7 {
+		t.Errorf("expected 7 init steps, got %d", len(net.Init))
+	}
+}
+
+func TestElaborateErrors(t *testing.T) {
+	inputs := []string{
+		`circuit foo {}`,
+		`circuit main { undefined (a) -> (b); }`,
+		`circuit main { (a, b) -> c; }`,
+		`circuit main { %m (a, b); !%m -> c; }`,
+		`circuit main { (1) -> %m; }`,
+		`circuit main { f (a) -> (b); } circuit f (x) -> (y) { g (x) -> (y); } circuit g (x) -> (y) { f (x) -> (y); }`,
+		`circuit main { f (a, b) -> (c); } circuit f (x) -> (y) { (x) -> y; }`,
+	}
+
+	for i, input := range inputs {
+		prog, err := parser.New(`name: "test";`+input, "test").Parse()
+		if err != nil {
+			t.Fatalf("(%d) %s", i, err)
+		}
+
+		if _, err := Elaborate(prog); err == nil {
+			t.Errorf("(%d) expected an elaboration error", i)
+		}
+	}
+}
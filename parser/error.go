@@ -2,29 +2,100 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/zac-garby/booleang/token"
 )
 
-// An Error represents an error encountered while parsing.
+// An Error represents an error encountered while parsing. Warnings
+// are also represented as Errors, but don't stop the parse.
 type Error struct {
 	Message string
 	Range   token.Range
+	Warning bool
 }
 
 func (e *Error) Error() string {
+	kind := "Error"
+	if e.Warning {
+		kind = "Warning"
+	}
+
 	return fmt.Sprintf(
-		"-* Parse Error @ [%s %d:%d-%d:%d] *- %s",
-		e.Range.Start.File,
-		e.Range.Start.Line,
-		e.Range.Start.Col,
-		e.Range.End.Line,
-		e.Range.End.Col,
+		"-* Parse %s @ [%s] *- %s",
+		kind,
+		e.Range,
 		e.Message,
 	)
 }
 
+// An ErrorList is a list of every error encountered while parsing
+// a program, in the order they were found.
+type ErrorList []*Error
+
+func (e ErrorList) Error() string {
+	var msgs []string
+
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Excerpt returns the line of text which a range starts on, with
+// carets underneath the characters in the range, e.g.
+//
+//	adder (a, b) => (c);
+//	             ^
+func Excerpt(text string, r token.Range) string {
+	lines := strings.Split(text, "\n")
+
+	if r.Start.Line < 1 || r.Start.Line > len(lines) {
+		return ""
+	}
+
+	line := strings.TrimRight(lines[r.Start.Line-1], "\r")
+
+	// columns are measured in bytes, so the characters before the
+	// range are replaced with spaces, or tabs to keep the caret
+	// lined up with tab-indented lines.
+	start := r.Start.Col - 1
+	if start > len(line) {
+		start = len(line)
+	} else if start < 0 {
+		start = 0
+	}
+
+	end := r.End.Col
+	if r.End.Line != r.Start.Line || end > len(line) {
+		end = len(line)
+	}
+
+	var indent []rune
+	for _, ch := range line[:start] {
+		if ch == '\t' {
+			indent = append(indent, '\t')
+		} else {
+			indent = append(indent, ' ')
+		}
+	}
+
+	width := len([]rune(line[start:end]))
+	if width < 1 {
+		width = 1
+	}
+
+	return fmt.Sprintf("%s\n%s%s", line, string(indent), strings.Repeat("^", width))
+}
+
 func (p *Parser) err(msg string, r token.Range, format ...interface{}) {
+	// only the first error at any position is reported, since the
+	// rest are usually caused by the first.
+	if n := len(p.Errors); n > 0 && p.Errors[n-1].Range.Start == r.Start {
+		return
+	}
+
 	p.Errors = append(p.Errors, &Error{
 		Message: fmt.Sprintf(msg, format...),
 		Range:   r,
@@ -0,0 +1,312 @@
+package parser
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/zac-garby/booleang/ast"
+	"github.com/zac-garby/booleang/token"
+)
+
+// The precedence levels of operators. An operator with a higher
+// precedence binds more tightly than one with a lower precedence.
+const (
+	lowest = iota
+	implies
+	or
+	xor
+	and
+	prefix
+)
+
+var precedences = map[string]int{
+	"=>": implies,
+	"→":  implies,
+	"|":  or,
+	"∨":  or,
+	"!|": or,
+	"⊽":  or,
+	"^":  xor,
+	"⊻":  xor,
+	"!^": xor,
+	"↔":  xor,
+	"≡":  xor,
+	"&":  and,
+	"∧":  and,
+	"!&": and,
+	"⊼":  and,
+}
+
+// reductions are the operators which can be used as a prefix, to
+// combine every bit of their operand into one.
+var reductions = map[string]bool{
+	"&":  true,
+	"∧":  true,
+	"|":  true,
+	"∨":  true,
+	"^":  true,
+	"⊻":  true,
+	"!&": true,
+	"⊼":  true,
+	"!|": true,
+	"⊽":  true,
+	"!^": true,
+	"↔":  true,
+	"≡":  true,
+}
+
+// rightAssociative contains the precedence levels whose operators
+// associate to the right, so a => b => c means a => (b => c).
+var rightAssociative = map[int]bool{
+	implies: true,
+}
+
+func (p *Parser) peekPrecedence() int {
+	if p.peekIs(token.Infix) {
+		if prec, ok := precedences[p.peek.Literal]; ok {
+			return prec
+		}
+	}
+
+	return lowest
+}
+
+// parseExpression parses a whole expression, starting at the
+// current token. If Compat is set, a warning is added if the
+// expression would have been parsed differently by the old parser.
+func (p *Parser) parseExpression() ast.Expression {
+	if !p.Compat || p.recording {
+		return p.parseExpr(lowest)
+	}
+
+	p.recording = true
+	p.recorded = []token.Token{p.cur}
+
+	expr := p.parseExpr(lowest)
+
+	p.recording = false
+
+	if expr != nil {
+		p.warnLegacy(expr, p.recorded)
+	}
+
+	return expr
+}
+
+// parseExpr parses an expression whose infix operators all bind
+// more tightly than the given precedence. Most operators of the same
+// precedence associate to the left, so a ^ b ^ c means (a ^ b) ^ c.
+func (p *Parser) parseExpr(precedence int) ast.Expression {
+	start := p.cur.Range.Start
+
+	left := p.parseOperand()
+	if left == nil {
+		return nil
+	}
+
+	for precedence < p.peekPrecedence() {
+		op := p.peek.Literal
+		prec := p.peekPrecedence()
+		p.next()
+		p.next()
+
+		next := prec
+		if rightAssociative[prec] {
+			next--
+		}
+
+		right := p.parseExpr(next)
+		if right == nil {
+			return nil
+		}
+
+		left = &ast.Infix{
+			Span:     p.span(start),
+			Left:     left,
+			Operator: op,
+			Right:    right,
+		}
+	}
+
+	return left
+}
+
+// parseOperand parses anything which can appear either side of an
+// infix operator: a literal, an identifier, a macro, a prefix
+// expression, a reduction or a parenthesised expression.
+func (p *Parser) parseOperand() ast.Expression {
+	start := p.cur.Range.Start
+
+	switch p.cur.Type {
+	case token.Ident:
+		var (
+			name   = p.cur.Literal
+			suffix ast.Int
+		)
+
+		if p.peekIs(token.LeftAngle) {
+			p.next()
+
+			if suffix = p.parseSuffix(); suffix == nil {
+				return nil
+			}
+		}
+
+		if p.peekIs(token.LeftBracket) {
+			p.next()
+
+			high, low, ok := p.parseIndex()
+			if !ok {
+				return nil
+			}
+
+			return &ast.Index{
+				Span:   p.span(start),
+				Name:   name,
+				Suffix: suffix,
+				High:   high,
+				Low:    low,
+			}
+		}
+
+		return &ast.Identifier{
+			Span:   p.span(start),
+			Value:  name,
+			Suffix: suffix,
+		}
+
+	case token.LeftBrace:
+		parts := p.parseExprs(token.RightBrace)
+		if parts == nil {
+			return nil
+		}
+
+		return &ast.Concat{
+			Span:  p.span(start),
+			Parts: parts,
+		}
+
+	case token.Number:
+		if p.cur.Literal == "0" || p.cur.Literal == "1" {
+			return &ast.Bit{
+				Span:  p.span(start),
+				Value: p.cur.Literal == "1",
+			}
+		}
+
+		value, ok := new(big.Int).SetString(p.cur.Literal, 10)
+		if !ok {
+			p.curErr("a number in an expression must be an integer. got %s", p.cur.Literal)
+			return nil
+		}
+
+		return &ast.Number{
+			Span:  p.span(start),
+			Value: value,
+		}
+
+	case token.Sized:
+		return p.parseSized()
+
+	case token.LeftParen:
+		p.next()
+
+		expr := p.parseExpr(lowest)
+		if expr == nil || !p.expect(token.RightParen) {
+			return nil
+		}
+
+		return expr
+
+	case token.Prefix:
+		op := p.cur.Literal
+		p.next()
+
+		right := p.parseExpr(prefix)
+		if right == nil {
+			return nil
+		}
+
+		return &ast.Prefix{
+			Span:     p.span(start),
+			Operator: op,
+			Right:    right,
+		}
+
+	case token.Infix:
+		if !reductions[p.cur.Literal] {
+			break
+		}
+
+		op := p.cur.Literal
+		p.next()
+
+		right := p.parseExpr(prefix)
+		if right == nil {
+			return nil
+		}
+
+		return &ast.Reduction{
+			Span:     p.span(start),
+			Operator: op,
+			Right:    right,
+		}
+
+	case token.Macro:
+		if !p.expect(token.Ident) {
+			return nil
+		}
+
+		return &ast.MacroExpr{
+			Span: p.span(start),
+			Name: p.cur.Literal,
+		}
+	}
+
+	p.curErr("unexpected token '%s' in an expression", p.cur.Type)
+
+	return nil
+}
+
+var bases = map[byte]int{
+	'b': 2,
+	'o': 8,
+	'd': 10,
+	'h': 16,
+}
+
+// parseSized parses a number with an explicit width and base, such
+// as 4'b1001 or 8'hFF.
+func (p *Parser) parseSized() ast.Expression {
+	var (
+		lit   = p.cur.Literal
+		quote = strings.IndexByte(lit, '\'')
+		base  = bases[lit[quote+1]|0x20]
+	)
+
+	width, err := strconv.Atoi(lit[:quote])
+	if err != nil || width < 1 {
+		p.curErr("the width of %s must be a positive integer", lit)
+		return nil
+	}
+
+	digits := strings.Replace(lit[quote+2:], "_", "", -1)
+
+	value, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		p.curErr("%s isn't a valid base %d number", lit[quote+2:], base)
+		return nil
+	}
+
+	if value.BitLen() > width {
+		p.curErr("%s doesn't fit in %d bits", lit, width)
+		return nil
+	}
+
+	return &ast.Number{
+		Span:  p.span(p.cur.Range.Start),
+		Value: value,
+		Width: width,
+	}
+}
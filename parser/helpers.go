@@ -12,6 +12,10 @@ func (p *Parser) next() {
 	p.cur = p.peek
 	p.peek = p.lex()
 
+	if p.recording {
+		p.recorded = append(p.recorded, p.cur)
+	}
+
 	if p.peek.Type == token.Illegal {
 		p.err(
 			"illegal token found: `%s`",
@@ -20,6 +24,15 @@ func (p *Parser) next() {
 	}
 }
 
+// span makes a span from the given position to the end of the
+// current token.
+func (p *Parser) span(start token.Position) ast.Span {
+	return ast.Span{
+		Start: start,
+		End:   p.cur.Range.End,
+	}
+}
+
 func (p *Parser) curIs(ts ...token.Type) bool {
 	for _, t := range ts {
 		if p.cur.Type == t {
@@ -57,7 +70,7 @@ func (p *Parser) parseInt() (int64, error) {
 func (p *Parser) parseDuration() *time.Duration {
 	val, err := p.parseInt()
 	if err != nil {
-		p.Errors = append(p.Errors, err)
+		p.curErr("a clock's duration must be a whole number. got %s", p.cur.Literal)
 		return nil
 	}
 
@@ -118,18 +131,19 @@ func (p *Parser) parseExprs(end token.Type) []ast.Expression {
 	return exprs
 }
 
-func (p *Parser) parseIdents(end token.Type) []string {
-	var idents []string
+func (p *Parser) parseIdents(end token.Type) ast.Parameters {
+	var idents ast.Parameters
 
 	if p.peekIs(end) {
 		p.next()
 		return idents
 	}
 
-	if !p.expect(token.Ident) {
+	ident := p.parseIdent()
+	if ident == nil {
 		return idents
 	}
-	idents = append(idents, p.cur.Literal)
+	idents = append(idents, *ident)
 
 	for p.peekIs(token.Comma) {
 		p.next()
@@ -139,10 +153,11 @@ func (p *Parser) parseIdents(end token.Type) []string {
 			return idents
 		}
 
-		if !p.expect(token.Ident) {
+		ident := p.parseIdent()
+		if ident == nil {
 			return idents
 		}
-		idents = append(idents, p.cur.Literal)
+		idents = append(idents, *ident)
 	}
 
 	if !p.expect(end) {
@@ -152,6 +167,92 @@ func (p *Parser) parseIdents(end token.Type) []string {
 	return idents
 }
 
+// parseIdent parses a parameter in a circuit's signature, which is
+// either a single register or a bus with a width, e.g. a[8].
+func (p *Parser) parseIdent() *ast.Parameter {
+	if !p.expect(token.Ident) {
+		return nil
+	}
+
+	ident := &ast.Parameter{
+		Name: p.cur.Literal,
+	}
+	start := p.cur.Range.Start
+
+	if p.peekIs(token.LeftBracket) {
+		p.next()
+
+		width, ok := p.parseWidth()
+		if !ok {
+			return nil
+		}
+
+		ident.Width = width
+	}
+
+	ident.Span = p.span(start)
+
+	return ident
+}
+
+// parseWidth parses the width of a bus, e.g. [8] or [N+1],
+// starting at the left bracket.
+func (p *Parser) parseWidth() (ast.Int, bool) {
+	p.next()
+
+	width := p.parseInteger()
+	if width == nil {
+		return nil, false
+	}
+
+	if c, ok := width.(*ast.Const); ok && c.Value < 1 {
+		p.err("the width of a bus must be positive. got %d", c.Range(), c.Value)
+		return nil, false
+	}
+
+	if !p.expect(token.RightBracket) {
+		return nil, false
+	}
+
+	return width, true
+}
+
+// parseIndex parses an index into a bus, e.g. [3] or [N-1:0],
+// starting at the left bracket.
+func (p *Parser) parseIndex() (high, low ast.Int, ok bool) {
+	p.next()
+
+	high = p.parseInteger()
+	if high == nil {
+		return nil, nil, false
+	}
+
+	low = high
+
+	if p.peekIs(token.Colon) {
+		p.next()
+		p.next()
+
+		low = p.parseInteger()
+		if low == nil {
+			return nil, nil, false
+		}
+	}
+
+	for _, n := range []ast.Int{high, low} {
+		if c, ok := n.(*ast.Const); ok && c.Value < 0 {
+			p.err("a bus index can't be negative. got %d", c.Range(), c.Value)
+			return nil, nil, false
+		}
+	}
+
+	if !p.expect(token.RightBracket) {
+		return nil, nil, false
+	}
+
+	return high, low, true
+}
+
 func (p *Parser) parseParams(end token.Type) ast.Parameters {
 	var params []ast.Parameter
 
@@ -194,6 +295,7 @@ func (p *Parser) parseParams(end token.Type) ast.Parameters {
 
 func (p *Parser) parseParam() *ast.Parameter {
 	param := &ast.Parameter{}
+	start := p.cur.Range.Start
 
 	if p.curIs(token.Macro) {
 		param.Macro = true
@@ -207,6 +309,29 @@ func (p *Parser) parseParam() *ast.Parameter {
 
 	param.Name = p.cur.Literal
 
+	if !param.Macro && p.peekIs(token.LeftAngle) {
+		p.next()
+
+		param.Suffix = p.parseSuffix()
+		if param.Suffix == nil {
+			return nil
+		}
+	}
+
+	if !param.Macro && p.peekIs(token.LeftBracket) {
+		p.next()
+
+		high, low, ok := p.parseIndex()
+		if !ok {
+			return nil
+		}
+
+		param.Indexed = true
+		param.High, param.Low = high, low
+	}
+
+	param.Span = p.span(start)
+
 	return param
 }
 
@@ -218,9 +343,56 @@ func (p *Parser) parseStatements() []ast.Statement {
 		stmt := p.parseStatement()
 		if stmt != nil {
 			stmts = append(stmts, stmt)
+		} else {
+			p.skipStatement()
 		}
 		p.next()
 	}
 
+	if p.curIs(token.EOF) {
+		p.curErr("expected %s, but got %s", token.RightBrace, token.EOF)
+	}
+
 	return stmts
 }
+
+// skipStatement skips the rest of a statement which couldn't be
+// parsed, so that parsing can carry on from the next one. It stops
+// at the semicolon ending the statement, or on the token before the
+// brace closing the enclosing block, or before a top-level keyword.
+// Any blocks opened while skipping are skipped entirely.
+func (p *Parser) skipStatement() {
+	depth := 0
+
+	for !p.curIs(token.EOF) {
+		switch {
+		case p.curIs(token.LeftBrace):
+			depth++
+
+		case p.curIs(token.RightBrace):
+			depth--
+			if depth <= 0 {
+				return
+			}
+
+		case p.curIs(token.Semi) && depth == 0:
+			return
+		}
+
+		if depth == 0 && p.peekIs(token.RightBrace, token.Circuit, token.Include, token.EOF) {
+			return
+		}
+
+		p.next()
+	}
+}
+
+// skipTopLevel skips tokens until the next top-level keyword, so
+// that parsing can carry on after an error in a circuit or include.
+func (p *Parser) skipTopLevel() {
+	for !p.curIs(token.EOF) && !p.peekIs(token.Circuit, token.Include, token.EOF) {
+		p.next()
+	}
+
+	p.next()
+}
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"github.com/zac-garby/booleang/ast"
+	"github.com/zac-garby/booleang/token"
+)
+
+// The precedence levels of arithmetic operators in integers.
+const (
+	sum = iota + 1
+	product
+)
+
+var arithPrecedences = map[token.Type]int{
+	token.Plus:  sum,
+	token.Minus: sum,
+	token.Star:  product,
+}
+
+// parseInteger parses an integer expression, such as the width of a
+// bus, starting at the current token.
+func (p *Parser) parseInteger() ast.Int {
+	return p.parseArith(sum)
+}
+
+// parseArith parses an integer expression whose operators all have
+// at least the given precedence. Operators associate to the left.
+func (p *Parser) parseArith(precedence int) ast.Int {
+	start := p.cur.Range.Start
+
+	left := p.parseIntOperand()
+	if left == nil {
+		return nil
+	}
+
+	for {
+		prec, ok := arithPrecedences[p.peek.Type]
+		if !ok || prec < precedence {
+			return left
+		}
+
+		op := p.peek.Literal
+		p.next()
+		p.next()
+
+		right := p.parseArith(prec + 1)
+		if right == nil {
+			return nil
+		}
+
+		left = &ast.Arith{
+			Span:     p.span(start),
+			Left:     left,
+			Operator: op,
+			Right:    right,
+		}
+	}
+}
+
+func (p *Parser) parseIntOperand() ast.Int {
+	start := p.cur.Range.Start
+
+	switch p.cur.Type {
+	case token.Number:
+		n, err := p.parseInt()
+		if err != nil {
+			p.curErr("expected an integer. got %s", p.cur.Literal)
+			return nil
+		}
+
+		return &ast.Const{
+			Span:  p.span(start),
+			Value: int(n),
+		}
+
+	case token.Ident:
+		return &ast.Var{
+			Span: p.span(start),
+			Name: p.cur.Literal,
+		}
+
+	case token.LeftParen:
+		p.next()
+
+		n := p.parseInteger()
+		if n == nil || !p.expect(token.RightParen) {
+			return nil
+		}
+
+		return n
+	}
+
+	p.curErr("unexpected token '%s' in an integer", p.cur.Type)
+
+	return nil
+}
+
+// parseGenerics parses the integers given to a generic circuit,
+// e.g. <8, N+1>, starting at the left angle bracket.
+func (p *Parser) parseGenerics() []ast.Int {
+	var args []ast.Int
+
+	for {
+		p.next()
+
+		arg := p.parseInteger()
+		if arg == nil {
+			return nil
+		}
+
+		args = append(args, arg)
+
+		if !p.peekIs(token.Comma) {
+			break
+		}
+
+		p.next()
+	}
+
+	if !p.expect(token.RightAngle) {
+		return nil
+	}
+
+	return args
+}
+
+// parseSuffix parses the suffix of an identifier, e.g. the <i+1>
+// in c<i+1>, starting at the left angle bracket.
+func (p *Parser) parseSuffix() ast.Int {
+	p.next()
+
+	suffix := p.parseInteger()
+	if suffix == nil || !p.expect(token.RightAngle) {
+		return nil
+	}
+
+	return suffix
+}
@@ -0,0 +1,211 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/zac-garby/booleang/ast"
+	"github.com/zac-garby/booleang/token"
+)
+
+// parseLegacyExpression parses an expression the way booleang used
+// to, before operators had precedence. Every infix operator had the
+// same precedence and associated to the right, and a prefix operator
+// applied to the whole expression after it, so !a & b meant !(a & b).
+// It's only used to warn about expressions whose meaning has changed.
+func (p *Parser) parseLegacyExpression() ast.Expression {
+	var (
+		left  ast.Expression
+		start = p.cur.Range.Start
+	)
+
+	switch p.cur.Type {
+	case token.Ident:
+		left = &ast.Identifier{
+			Span:  p.span(start),
+			Value: p.cur.Literal,
+		}
+
+	case token.Number:
+		if !(p.cur.Literal == "0" || p.cur.Literal == "1") {
+			p.curErr("a bit literal must be 0 or 1")
+			return nil
+		}
+
+		left = &ast.Bit{
+			Span:  p.span(start),
+			Value: p.cur.Literal == "1",
+		}
+
+	case token.LeftParen:
+		p.next()
+		left = p.parseLegacyExpression()
+		if !p.expect(token.RightParen) {
+			return nil
+		}
+
+	case token.Prefix:
+		op := p.cur.Literal
+		p.next()
+
+		prefix := &ast.Prefix{
+			Operator: op,
+			Right:    p.parseLegacyExpression(),
+		}
+		prefix.Span = p.span(start)
+		left = prefix
+
+	case token.Macro:
+		if !p.expect(token.Ident) {
+			return nil
+		}
+		left = &ast.MacroExpr{
+			Span: p.span(start),
+			Name: p.cur.Literal,
+		}
+	}
+
+	if p.peekIs(token.Infix) {
+		op := p.peek.Literal
+		p.next()
+		p.next()
+		right := p.parseLegacyExpression()
+		left = &ast.Infix{
+			Span:     p.span(start),
+			Left:     left,
+			Operator: op,
+			Right:    right,
+		}
+	}
+
+	return left
+}
+
+// equivalent checks whether two expressions mean the same thing, by
+// comparing their values for every combination of their identifiers.
+// Expressions which are too big to compare like this, or which
+// contain macros, are only equivalent if they're written the same.
+func equivalent(a, b ast.Expression) bool {
+	if a.String() == b.String() {
+		return true
+	}
+
+	var idents []string
+	if !identifiers(a, &idents) || !identifiers(b, &idents) || len(idents) > 16 {
+		return false
+	}
+
+	values := make(map[string]bool)
+
+	for n := 0; n < 1<<uint(len(idents)); n++ {
+		for i, ident := range idents {
+			values[ident] = n&(1<<uint(i)) != 0
+		}
+
+		if evaluate(a, values) != evaluate(b, values) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// identifiers adds each identifier in an expression to the list,
+// unless it's already there. If the expression contains anything
+// other than bits, identifiers and operators, it returns false.
+func identifiers(expr ast.Expression, idents *[]string) bool {
+	switch ex := expr.(type) {
+	case *ast.Bit:
+		return true
+
+	case *ast.Identifier:
+		for _, ident := range *idents {
+			if ident == ex.Value {
+				return true
+			}
+		}
+
+		*idents = append(*idents, ex.Value)
+		return true
+
+	case *ast.Prefix:
+		return identifiers(ex.Right, idents)
+
+	case *ast.Infix:
+		return identifiers(ex.Left, idents) && identifiers(ex.Right, idents)
+	}
+
+	return false
+}
+
+func evaluate(expr ast.Expression, values map[string]bool) bool {
+	switch ex := expr.(type) {
+	case *ast.Bit:
+		return ex.Value
+
+	case *ast.Identifier:
+		return values[ex.Value]
+
+	case *ast.Prefix:
+		return !evaluate(ex.Right, values)
+
+	case *ast.Infix:
+		left, right := evaluate(ex.Left, values), evaluate(ex.Right, values)
+
+		switch ex.Operator {
+		case "&", "∧":
+			return left && right
+		case "|", "∨":
+			return left || right
+		case "^", "⊻":
+			return left != right
+		case "!&", "⊼":
+			return !(left && right)
+		case "!|", "⊽":
+			return !(left || right)
+		case "!^", "↔", "≡":
+			return left == right
+		case "=>", "→":
+			return !left || right
+		}
+	}
+
+	return false
+}
+
+// warnLegacy parses the tokens of an expression the old way, and
+// adds a warning if the result is different to how it's parsed now.
+func (p *Parser) warnLegacy(expr ast.Expression, toks []token.Token) {
+	i := 0
+
+	legacy := &Parser{
+		lex: func() token.Token {
+			if i < len(toks) {
+				i++
+				return toks[i-1]
+			}
+
+			return token.Token{Type: token.EOF}
+		},
+	}
+
+	legacy.next()
+	legacy.next()
+
+	// if the old parser didn't get to the end of the expression,
+	// it uses syntax which didn't exist back then.
+	old := legacy.parseLegacyExpression()
+	if old == nil || len(legacy.Errors) > 0 || !legacy.peekIs(token.EOF) {
+		return
+	}
+
+	if !equivalent(old, expr) {
+		p.Warnings = append(p.Warnings, &Error{
+			Message: fmt.Sprintf(
+				"this expression used to mean %s, but now means %s. add parentheses to keep the old meaning",
+				old, expr,
+			),
+			Range:   expr.Range(),
+			Warning: true,
+		})
+	}
+}
@@ -9,19 +9,30 @@ import (
 // A Parser takes a sequence of tokens and constructs an abstract
 // syntax tree.
 type Parser struct {
-	Errors []error
+	Errors ErrorList
+
+	// Compat enables a warning wherever an expression is parsed
+	// differently to how it was before operators had precedence,
+	// when every operator associated to the right, and !a & b
+	// meant !(a & b).
+	Compat   bool
+	Warnings ErrorList
 
 	lex       func() token.Token
 	text      string
 	cur, peek token.Token
+
+	// while recording, each token is added to recorded as it
+	// becomes the current token.
+	recording bool
+	recorded  []token.Token
 }
 
 // New makes a new `Parser` instance.
 func New(text, file string) *Parser {
 	p := &Parser{
-		lex:    lexer.New(text, file),
-		text:   text,
-		Errors: make([]error, 0),
+		lex:  lexer.New(text, file),
+		text: text,
 	}
 
 	p.next()
@@ -30,11 +41,13 @@ func New(text, file string) *Parser {
 	return p
 }
 
+// Parse parses the whole program. If there are any errors, they
+// are all returned in an ErrorList.
 func (p *Parser) Parse() (prog *ast.Program, err error) {
 	prog = p.parse()
 
 	if len(p.Errors) > 0 {
-		return nil, p.Errors[0]
+		return nil, p.Errors
 	}
 
 	return prog, nil
@@ -45,59 +58,70 @@ func (p *Parser) parse() *ast.Program {
 		Name: "unnamed",
 	}
 
-	if p.curIs(token.Name) {
-		if !p.expect(token.Colon) {
-			return nil
-		}
-
-		if !p.expect(token.String) {
-			return nil
-		}
-
-		prog.Name = p.cur.Literal
+	start := p.cur.Range.Start
 
-		if !p.expect(token.Semi) {
-			return nil
+	if p.curIs(token.Name) {
+		if p.parseName(prog) {
+			p.next()
+		} else {
+			p.skipTopLevel()
 		}
 	}
 
-	for !p.peekIs(token.EOF) {
-		p.next()
+	for !p.curIs(token.EOF) {
+		ok := true
 
-		if p.cur.Type == token.Circuit {
+		switch p.cur.Type {
+		case token.Circuit:
 			circuit := p.parseCircuit()
-			if circuit == nil {
-				return nil
+			if circuit != nil {
+				prog.Circuits = append(prog.Circuits, circuit)
 			}
 
-			prog.Circuits = append(prog.Circuits, circuit)
-		} else if p.cur.Type == token.Include {
-			byName := false
+			ok = circuit != nil
 
-			if p.peekIs(token.Name) {
-				p.next()
-				byName = true
+		case token.Include:
+			var include *ast.Include
+			if include = p.parseInclude(); include != nil {
+				prog.Includes = append(prog.Includes, *include)
 			}
 
-			path, ok := p.parseInclude()
-			if !ok {
-				return nil
-			}
+			ok = include != nil
 
-			prog.Includes = append(prog.Includes, ast.Include{
-				ByName: byName,
-				Value:  path,
-			})
-		} else {
+		default:
 			p.curErr("only circuits and include statements can be written in the top-level of a file")
-			return nil
+			ok = false
+		}
+
+		if ok {
+			p.next()
+		} else {
+			p.skipTopLevel()
 		}
 	}
 
+	prog.Span = p.span(start)
+
 	return prog
 }
 
+func (p *Parser) parseName(prog *ast.Program) bool {
+	if !p.expect(token.Colon) {
+		return false
+	}
+
+	if !p.expect(token.String) {
+		return false
+	}
+
+	prog.Name = p.cur.Literal
+
+	return p.expect(token.Semi)
+}
+
 func (p *Parser) parseCircuit() *ast.Circuit {
+	start := p.cur.Range.Start
+
 	if !p.expect(token.Ident) {
 		return nil
 	}
@@ -106,6 +130,28 @@ func (p *Parser) parseCircuit() *ast.Circuit {
 		Name: p.cur.Literal,
 	}
 
+	if p.peekIs(token.LeftAngle) {
+		p.next()
+
+		for {
+			if !p.expect(token.Ident) {
+				return nil
+			}
+
+			circ.Generics = append(circ.Generics, p.cur.Literal)
+
+			if !p.peekIs(token.Comma) {
+				break
+			}
+
+			p.next()
+		}
+
+		if !p.expect(token.RightAngle) {
+			return nil
+		}
+	}
+
 	if p.peekIs(token.LeftParen) {
 		p.next()
 		circ.Inputs = p.parseIdents(token.RightParen)
@@ -125,100 +171,96 @@ func (p *Parser) parseCircuit() *ast.Circuit {
 	}
 
 	circ.Statements = p.parseStatements()
+	circ.Span = p.span(start)
 
 	return circ
 }
 
-func (p *Parser) parseInclude() (path string, ok bool) {
+func (p *Parser) parseInclude() *ast.Include {
+	include := &ast.Include{}
+	start := p.cur.Range.Start
+
+	if p.peekIs(token.Name) {
+		p.next()
+		include.ByName = true
+	}
+
 	if !p.expect(token.String) {
-		return "", false
+		return nil
 	}
 
-	path = p.cur.Literal
+	include.Value = p.cur.Literal
 
 	if !p.expect(token.Semi) {
-		return "", false
+		return nil
 	}
 
-	return path, true
+	include.Span = p.span(start)
+
+	return include
 }
 
-func (p *Parser) parseExpression() ast.Expression {
-	var left ast.Expression
+func (p *Parser) parseStatement() ast.Statement {
+	start := p.cur.Range.Start
 
 	switch p.cur.Type {
-	case token.Ident:
-		left = &ast.Identifier{
-			Value: p.cur.Literal,
-		}
-
-	case token.Number:
-		if !(p.cur.Literal == "0" || p.cur.Literal == "1") {
-			p.curErr("a bit literal must be 0 or 1")
+	case token.Macro:
+		stmt := &ast.MacroStmt{}
+		if !p.expect(token.Ident) {
 			return nil
 		}
+		stmt.Name = p.cur.Literal
 
-		left = &ast.Bit{
-			Value: p.cur.Literal == "1",
+		if !p.expect(token.LeftParen) {
+			return nil
 		}
+		stmt.Registers = p.parseParams(token.RightParen)
 
-	case token.LeftParen:
-		p.next()
-		left = p.parseExpression()
-		if !p.expect(token.RightParen) {
+		if !p.expect(token.Semi) {
 			return nil
 		}
 
-	case token.Prefix:
-		op := p.cur.Literal
-		p.next()
-		left = &ast.Prefix{
-			Operator: op,
-			Right:    p.parseExpression(),
-		}
+		stmt.Span = p.span(start)
 
-	case token.Macro:
+		return stmt
+
+	case token.For:
+		stmt := &ast.For{}
 		if !p.expect(token.Ident) {
 			return nil
 		}
-		left = &ast.MacroExpr{
-			Name: p.cur.Literal,
+		stmt.Var = p.cur.Literal
+
+		if !p.expect(token.Ident) {
+			return nil
 		}
-	}
 
-	if p.peekIs(token.Infix) {
-		op := p.peek.Literal
-		p.next()
-		p.next()
-		right := p.parseExpression()
-		left = &ast.Infix{
-			Left:     left,
-			Operator: op,
-			Right:    right,
+		if p.cur.Literal != "in" {
+			p.curErr("expected 'in' after the loop variable. got %s", p.cur.Literal)
+			return nil
 		}
-	}
+		p.next()
 
-	return left
-}
+		if stmt.From = p.parseInteger(); stmt.From == nil {
+			return nil
+		}
 
-func (p *Parser) parseStatement() ast.Statement {
-	switch p.cur.Type {
-	case token.Macro:
-		stmt := &ast.MacroStmt{}
-		if !p.expect(token.Ident) {
+		if !p.expect(token.DotDot) {
 			return nil
 		}
-		stmt.Name = p.cur.Literal
+		p.next()
 
-		if !p.expect(token.LeftParen) {
+		if stmt.To = p.parseInteger(); stmt.To == nil {
 			return nil
 		}
-		stmt.Registers = p.parseParams(token.RightParen)
 
-		if !p.expect(token.Semi) {
+		if !p.expect(token.LeftBrace) {
 			return nil
 		}
 
+		stmt.Body = p.parseStatements()
+		stmt.Span = p.span(start)
+
 		return stmt
 
 	case token.Clock:
@@ -241,6 +283,25 @@ func (p *Parser) parseStatement() ast.Statement {
 			}
 
 			stmt.Counter = p.cur.Literal
+
+			if p.peekIs(token.LeftBracket) {
+				p.next()
+
+				if !p.expect(token.Number) {
+					return nil
+				}
+
+				width, err := p.parseInt()
+				if err != nil || width < 1 {
+					p.curErr("a clock counter's width must be a positive integer. got %s", p.cur.Literal)
+					return nil
+				}
+				stmt.Width = int(width)
+
+				if !p.expect(token.RightBracket) {
+					return nil
+				}
+			}
 		}
 
 		if !p.expect(token.LeftBrace) {
@@ -248,14 +309,45 @@ func (p *Parser) parseStatement() ast.Statement {
 		}
 
 		stmt.Body = p.parseStatements()
+		stmt.Span = p.span(start)
 
 		return stmt
 
 	case token.Ident:
+		if p.peekIs(token.LeftBracket) {
+			stmt := &ast.BusStmt{
+				Name: p.cur.Literal,
+			}
+			p.next()
+
+			width, ok := p.parseWidth()
+			if !ok {
+				return nil
+			}
+			stmt.Width = width
+
+			if !p.expect(token.Semi) {
+				return nil
+			}
+
+			stmt.Span = p.span(start)
+
+			return stmt
+		}
+
 		stmt := &ast.Call{
 			Circuit: p.cur.Literal,
 		}
 
+		if p.peekIs(token.LeftAngle) {
+			p.next()
+
+			stmt.Generics = p.parseGenerics()
+			if stmt.Generics == nil {
+				return nil
+			}
+		}
+
 		if !p.expect(token.LeftParen) {
 			return nil
 		}
@@ -266,6 +358,8 @@ func (p *Parser) parseStatement() ast.Statement {
 				return nil
 			}
 
+			stmt.Span = p.span(start)
+
 			return stmt
 		}
 		p.next()
@@ -279,9 +373,11 @@ func (p *Parser) parseStatement() ast.Statement {
 			return nil
 		}
 
+		stmt.Span = p.span(start)
+
 		return stmt
 
-	case token.Number, token.Prefix, token.LeftParen:
+	case token.Number, token.Sized, token.Prefix, token.Infix, token.LeftParen, token.LeftBrace:
 		var stmt *ast.Pipe
 
 		if p.cur.Type == token.LeftParen {
@@ -318,6 +414,8 @@ func (p *Parser) parseStatement() ast.Statement {
 			return nil
 		}
 
+		stmt.Span = p.span(start)
+
 		return stmt
 
 	default:
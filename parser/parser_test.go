@@ -0,0 +1,300 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zac-garby/booleang/ast"
+	. "github.com/zac-garby/booleang/parser"
+	"github.com/zac-garby/booleang/token"
+)
+
+func TestParse(t *testing.T) {
+	input := `circuit invert (a) -> (b) {
+	!a -> b;
+}
+
+include "adder.bl";
+
+circuit main {
+	%n (x, y);
+	invert (x) -> (y);
+
+	clock 1s %tick[4] {
+		(x, !y) -> %n;
+	}
+}`
+
+	prog, err := New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prog.Name != "unnamed" {
+		t.Errorf("expected an unnamed program, got %s", prog.Name)
+	}
+
+	if len(prog.Circuits) != 2 || prog.Circuits[0].Name != "invert" || prog.Circuits[1].Name != "main" {
+		t.Errorf("expected circuits invert and main, got %s", prog)
+	}
+
+	if len(prog.Includes) != 1 || prog.Includes[0].Value != "adder.bl" {
+		t.Errorf("expected an include of adder.bl, got %v", prog.Includes)
+	}
+
+	if n := len(prog.Circuits[1].Statements); n != 3 {
+		t.Errorf("expected 3 statements in main, got %d", n)
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	input := `name: "errors";
+
+circuit main {
+	0 -> a;
+	(0, 1 -> %b;
+	foo (a) ~> (b);
+	clock 1s {
+		?;
+		1 -> a;
+	}
+	1 -> $;
+}
+
+circuit (a) -> (b) {}
+
+circuit fine {
+	include;
+}
+
+) include "foo.bl";
+`
+
+	_, err := New(input, "test").Parse()
+
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expected an ErrorList, got %v", err)
+	}
+
+	lines := []int{5, 6, 8, 11, 14, 17, 20}
+
+	if len(list) != len(lines) {
+		t.Errorf("expected %d errors, got %d", len(lines), len(list))
+		t.Log(err)
+	}
+
+	for i, line := range lines {
+		if i < len(list) && list[i].Range.Start.Line != line {
+			t.Errorf("(%d) expected an error on line %d, got %s", i, line, list[i])
+		}
+	}
+
+	if len(list) > 0 && !strings.HasPrefix(list[0].Error(), "-* Parse Error @ [test:5:") {
+		t.Errorf("expected the error's range to be written as file:line:col, got %s", list[0])
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	text := "line one\n\tfoo (a) => (b);\nline three"
+
+	got := Excerpt(text, token.Range{
+		Start: token.Position{Line: 2, Col: 10},
+		End:   token.Position{Line: 2, Col: 11},
+	})
+
+	exp := "\tfoo (a) => (b);\n\t        ^^"
+	if got != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, got)
+	}
+
+	if got := Excerpt(text, token.Range{}); got != "" {
+		t.Errorf("expected nothing for an invalid range, got %q", got)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	tests := map[string]string{
+		"a | b & c":     "(a | (b & c))",
+		"a & b | c":     "((a & b) | c)",
+		"a ^ b & c | d": "((a ^ (b & c)) | d)",
+		"a ^ b ^ c":     "((a ^ b) ^ c)",
+		"!a & b":        "(!a & b)",
+		"!(a & b)":      "!(a & b)",
+		"¬a ∨ b ∧ !!c":  "(¬a ∨ (b ∧ !!c))",
+		"(a | b) & c":   "((a | b) & c)",
+		"a => b => c":   "(a => (b => c))",
+		"a | b → c":     "((a | b) → c)",
+		"a !& b !| c":   "((a !& b) !| c)",
+		"a ≡ b ⊼ c":     "(a ≡ (b ⊼ c))",
+	}
+
+	for in, exp := range tests {
+		prog, err := New("circuit main { ("+in+") -> x; }", "test").Parse()
+		if err != nil {
+			t.Errorf("%s: %s", in, err)
+			continue
+		}
+
+		pipe := prog.Circuits[0].Statements[0].(*ast.Pipe)
+		if got := pipe.Inputs[0].String(); got != exp {
+			t.Errorf("%s: expected %s, got %s", in, exp, got)
+		}
+	}
+}
+
+func TestCompat(t *testing.T) {
+	input := `circuit main {
+	(a & b) -> x;
+	(a & b | c) -> x;
+	!a & b -> x;
+	(a & (b | c), a | b | c, a ^ b & c) -> (x, y, z);
+}`
+
+	p := New(input, "test")
+	p.Compat = true
+
+	if _, err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []int{3, 4}
+
+	if len(p.Warnings) != len(lines) {
+		t.Errorf("expected %d warnings, got %d", len(lines), len(p.Warnings))
+		t.Log(p.Warnings)
+	}
+
+	for i, line := range lines {
+		if i < len(p.Warnings) && p.Warnings[i].Range.Start.Line != line {
+			t.Errorf("(%d) expected a warning on line %d, got %s", i, line, p.Warnings[i])
+		}
+	}
+}
+
+func TestBuses(t *testing.T) {
+	input := `circuit swap (a[8], c) -> (b[8]) {
+	x[4];
+	{a[7:4], a[3:0]} -> b;
+	(a[0] & c, a[1]) -> (x[3], x[0]);
+}`
+
+	prog, err := New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	circ := prog.Circuits[0]
+
+	if got := circ.Inputs.String(); got != "a[8], c" {
+		t.Errorf("expected inputs a[8], c, got %s", got)
+	}
+
+	if bus, ok := circ.Statements[0].(*ast.BusStmt); !ok || bus.Name != "x" || bus.Width.String() != "4" {
+		t.Errorf("expected a declaration of x[4], got %s", circ.Statements[0])
+	}
+
+	pipe := circ.Statements[1].(*ast.Pipe)
+	if got := pipe.Inputs[0].String(); got != "{a[7:4], a[3:0]}" {
+		t.Errorf("expected {a[7:4], a[3:0]}, got %s", got)
+	}
+
+	pipe = circ.Statements[2].(*ast.Pipe)
+	if out := pipe.Outputs[0]; !out.Indexed || out.High.String() != "3" || out.Low.String() != "3" {
+		t.Errorf("expected x[3], got %s", out)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := map[string]string{
+		"4'b1001":  "4'd9",
+		"8'hFF":    "8'd255",
+		"8'd200":   "8'd200",
+		"12'o7_7":  "12'd63",
+		"200":      "200",
+		"-3":       "-3",
+		"4'b10011": "4'b10011 doesn't fit in 4 bits",
+		"8'hFG":    "FG isn't a valid base 16 number",
+		"0'b0":     "the width of 0'b0 must be a positive integer",
+		"2.5":      "must be an integer",
+	}
+
+	for in, exp := range tests {
+		prog, err := New("circuit main { ("+in+") -> x; }", "test").Parse()
+		if err != nil {
+			if !strings.Contains(err.Error(), exp) {
+				t.Errorf("%s: expected %q, got %s", in, exp, err)
+			}
+
+			continue
+		}
+
+		pipe := prog.Circuits[0].Statements[0].(*ast.Pipe)
+		if got := pipe.Inputs[0].String(); got != exp {
+			t.Errorf("%s: expected %s, got %s", in, exp, got)
+		}
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	input := `circuit shift<N> (a[N]) -> (b[N+1]) {
+	{0, a[N-1:0]} -> b;
+	pad<N, 2*(N+1)> (a) -> (c);
+}`
+
+	prog, err := New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	circ := prog.Circuits[0]
+
+	if len(circ.Generics) != 1 || circ.Generics[0] != "N" {
+		t.Errorf("expected a generic parameter N, got %v", circ.Generics)
+	}
+
+	if got := circ.Outputs.String(); got != "b[N+1]" {
+		t.Errorf("expected outputs b[N+1], got %s", got)
+	}
+
+	pipe := circ.Statements[0].(*ast.Pipe)
+	if got := pipe.Inputs[0].String(); got != "{<bit 0>, a[N-1:0]}" {
+		t.Errorf("expected {<bit 0>, a[N-1:0]}, got %s", got)
+	}
+
+	call := circ.Statements[1].(*ast.Call)
+	if len(call.Generics) != 2 || call.Generics[1].String() != "2*(N+1)" {
+		t.Errorf("expected generics <N, 2*(N+1)>, got %s", call)
+	}
+}
+
+func TestFor(t *testing.T) {
+	input := `circuit main {
+	for i in 1..N*2 {
+		adder (a[i], b[i], c<i>) -> (s[i-1], c<i+1>);
+	}
+}`
+
+	prog, err := New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loop, ok := prog.Circuits[0].Statements[0].(*ast.For)
+	if !ok {
+		t.Fatalf("expected a for loop, got %s", prog.Circuits[0].Statements[0])
+	}
+
+	if loop.Var != "i" || loop.From.String() != "1" || loop.To.String() != "N*2" {
+		t.Errorf("expected for i in 1..N*2, got %s", loop)
+	}
+
+	call := loop.Body[0].(*ast.Call)
+	if got := call.Inputs[2].String(); got != "c<i>" {
+		t.Errorf("expected c<i>, got %s", got)
+	}
+
+	if got := call.Outputs.String(); got != "s[i-1], c<i+1>" {
+		t.Errorf("expected s[i-1], c<i+1>, got %s", got)
+	}
+}
@@ -0,0 +1,152 @@
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// ParseBits parses a value into the given number of bits, least
+// significant bit first. The value can be written in decimal, or in
+// binary or hexadecimal with a 0b or 0x prefix. Negative decimals
+// are stored in two's complement. Underscores can be used to
+// separate digits, e.g. 0b1001_0110.
+func ParseBits(s string, width int) ([]bool, error) {
+	s = strings.Replace(strings.TrimSpace(s), "_", "", -1)
+
+	if s == "" {
+		return nil, errors.New("no value given")
+	}
+
+	var (
+		n    = new(big.Int)
+		ok   bool
+		base = "a decimal"
+	)
+
+	switch {
+	case strings.HasPrefix(s, "0b"), strings.HasPrefix(s, "0B"):
+		_, ok = n.SetString(s[2:], 2)
+		base = "a binary"
+
+	case strings.HasPrefix(s, "0x"), strings.HasPrefix(s, "0X"):
+		_, ok = n.SetString(s[2:], 16)
+		base = "a hexadecimal"
+
+	default:
+		_, ok = n.SetString(s, 10)
+	}
+
+	if !ok || (base != "a decimal" && n.Sign() < 0) {
+		return nil, fmt.Errorf("%s isn't %s number", s, base)
+	}
+
+	if n.Sign() < 0 {
+		min := new(big.Int).Lsh(big.NewInt(1), uint(width-1))
+		if n.Cmp(min.Neg(min)) < 0 {
+			return nil, fmt.Errorf("%s doesn't fit in %d bits", s, width)
+		}
+
+		n.Add(n, new(big.Int).Lsh(big.NewInt(1), uint(width)))
+	} else if n.BitLen() > width {
+		return nil, fmt.Errorf("%s doesn't fit in %d bits", s, width)
+	}
+
+	bits := make([]bool, width)
+	for i := range bits {
+		bits[i] = n.Bit(i) == 1
+	}
+
+	return bits, nil
+}
+
+// A Terminal reads values typed in by a user, prompting them for
+// each one and asking again if they type something invalid.
+type Terminal struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewTerminal makes a Terminal which reads from in and writes its
+// prompts to out.
+func NewTerminal(in io.Reader, out io.Writer) *Terminal {
+	return &Terminal{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// Read prompts for a value and reads it into the given number of
+// bits, least significant first.
+func (t *Terminal) Read(label string, width int) ([]bool, error) {
+	for {
+		fmt.Fprintf(t.out, "%s [%d bits]: ", label, width)
+
+		line, err := t.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return nil, err
+		}
+
+		bits, perr := ParseBits(line, width)
+		if perr == nil {
+			return bits, nil
+		}
+
+		fmt.Fprintf(t.out, "invalid input: %s\n", perr)
+
+		if err == io.EOF {
+			return nil, err
+		}
+	}
+}
+
+// A Script reads values from a stimulus file, one per line, so
+// that a circuit can be driven without anyone at the keyboard.
+// Empty lines and comments starting with # are skipped.
+type Script struct {
+	name    string
+	line    int
+	scanner *bufio.Scanner
+}
+
+// NewScript makes a Script which reads from r. The name is used in
+// error messages.
+func NewScript(r io.Reader, name string) *Script {
+	return &Script{
+		name:    name,
+		scanner: bufio.NewScanner(r),
+	}
+}
+
+// Read reads the next value from the script into the given number
+// of bits, least significant first.
+func (s *Script) Read(label string, width int) ([]bool, error) {
+	for s.scanner.Scan() {
+		s.line++
+
+		text := s.scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		bits, err := ParseBits(text, width)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid input for %s: %s", s.name, s.line, label, err)
+		}
+
+		return bits, nil
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("%s: ran out of inputs while reading %s", s.name, label)
+}
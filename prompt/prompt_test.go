@@ -0,0 +1,93 @@
+package prompt_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/zac-garby/booleang/prompt"
+)
+
+func bits(s string) []bool {
+	var bs []bool
+
+	for _, ch := range s {
+		bs = append(bs, ch == '1')
+	}
+
+	return bs
+}
+
+func TestParseBits(t *testing.T) {
+	valid := map[string][]bool{
+		"9":         bits("1001"),
+		"0b0110":    bits("0110"),
+		"0xA":       bits("0101"),
+		" 0b1_000 ": bits("0001"),
+		"-1":        bits("1111"),
+		"-8":        bits("0001"),
+		"0":         bits("0000"),
+	}
+
+	for in, exp := range valid {
+		got, err := ParseBits(in, 4)
+		if err != nil {
+			t.Errorf("%q: %s", in, err)
+			continue
+		}
+
+		for i := range exp {
+			if got[i] != exp[i] {
+				t.Errorf("%q: expected %v, got %v", in, exp, got)
+				break
+			}
+		}
+	}
+
+	for _, in := range []string{"16", "-9", "0b10000", "0x1F", "", "abc", "0b102", "0x-1"} {
+		if _, err := ParseBits(in, 4); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	var out bytes.Buffer
+	term := NewTerminal(strings.NewReader("banana\n99\n3\n"), &out)
+
+	got, err := term.Read("%n", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got[0] || !got[1] || got[2] || got[3] {
+		t.Errorf("expected 3, got %v", got)
+	}
+
+	if n := strings.Count(out.String(), "invalid input"); n != 2 {
+		t.Errorf("expected to re-prompt twice, re-prompted %d times", n)
+	}
+}
+
+func TestScript(t *testing.T) {
+	script := NewScript(strings.NewReader("# stimulus\n\n1 # one\n0b10\nbad\n"), "test")
+
+	for _, exp := range []int{1, 2} {
+		got, err := script.Read("x", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got[0] != (exp&1 == 1) || got[1] != (exp&2 == 2) {
+			t.Errorf("expected %d, got %v", exp, got)
+		}
+	}
+
+	if _, err := script.Read("x", 2); err == nil || !strings.Contains(err.Error(), "test:5") {
+		t.Errorf("expected an error on line 5, got %v", err)
+	}
+
+	if _, err := script.Read("x", 2); err == nil {
+		t.Errorf("expected an error once the script has run out")
+	}
+}
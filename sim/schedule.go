@@ -0,0 +1,117 @@
+package sim
+
+import (
+	"container/heap"
+	"time"
+)
+
+// A Mode specifies how Run relates simulated time to real time.
+type Mode int
+
+const (
+	// RealTime synchronises the simulation with the wall clock, so
+	// a clock with a period of 1s fires once every real second.
+	RealTime Mode = iota
+
+	// Virtual runs the simulation as fast as possible, without
+	// waiting between steps.
+	Virtual
+)
+
+// An event is the next firing of a clock.
+type event struct {
+	at    time.Duration
+	clock int
+	first bool
+}
+
+// A queue is a priority queue of events, ordered by the time they
+// happen at. Clocks firing at the same time fire in the order they
+// were declared in.
+type queue []event
+
+func (q queue) Len() int {
+	return len(q)
+}
+
+func (q queue) Less(i, j int) bool {
+	if q[i].at == q[j].at {
+		return q[i].clock < q[j].clock
+	}
+
+	return q[i].at < q[j].at
+}
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *queue) Push(x interface{}) {
+	*q = append(*q, x.(event))
+}
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
+
+// schedule starts the clocks which aren't nested in other clocks.
+// A nested clock is started the first time its parent fires, so
+// its first tick happens one of its own periods after that.
+func (s *Simulator) schedule() {
+	s.children = make([][]int, len(s.net.Clocks))
+
+	for i, clock := range s.net.Clocks {
+		if clock.Parent >= 0 {
+			s.children[clock.Parent] = append(s.children[clock.Parent], i)
+		} else {
+			s.start(i)
+		}
+	}
+}
+
+func (s *Simulator) start(clock int) {
+	heap.Push(&s.queue, event{
+		at:    s.time + s.net.Clocks[clock].Period,
+		clock: clock,
+		first: true,
+	})
+}
+
+// fire executes the next event in the queue, and schedules the next
+// tick of its clock.
+func (s *Simulator) fire() error {
+	ev := heap.Pop(&s.queue).(event)
+	s.time = ev.at
+
+	clock := s.net.Clocks[ev.clock]
+
+	if err := s.exec(clock.Body); err != nil {
+		return err
+	}
+
+	if ev.first {
+		for _, child := range s.children[ev.clock] {
+			s.start(child)
+		}
+	}
+
+	heap.Push(&s.queue, event{
+		at:    ev.at + clock.Period,
+		clock: ev.clock,
+	})
+
+	return nil
+}
+
+// Next returns the time at which the next clock will fire. If no
+// clocks are running, ok will be false.
+func (s *Simulator) Next() (at time.Duration, ok bool) {
+	if len(s.queue) == 0 {
+		return 0, false
+	}
+
+	return s.queue[0].at, true
+}
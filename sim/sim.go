@@ -0,0 +1,366 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zac-garby/booleang/ast"
+	"github.com/zac-garby/booleang/builtin"
+	"github.com/zac-garby/booleang/netlist"
+)
+
+// DefaultStepSize is the step size of a new Simulator.
+const DefaultStepSize = time.Second
+
+// A Reader provides the values read by input builtins.
+type Reader interface {
+	// Read reads a value into the given number of bits, least
+	// significant bit first. The label describes what is being
+	// read, e.g. "%na".
+	Read(label string, width int) ([]bool, error)
+}
+
+// A Simulator steps through the execution of a netlist, keeping
+// track of the state of each of its registers.
+type Simulator struct {
+	// StepSize is the furthest Run advances the simulation in a
+	// single step. Run also steps whenever a clock is due to fire.
+	StepSize time.Duration
+
+	// Mode decides whether Run waits for real time to pass.
+	Mode Mode
+
+	// Limit, if positive, is the amount of simulated time after
+	// which Run returns.
+	Limit time.Duration
+
+	// OnStep, if non-nil, is called after each step made by Run.
+	OnStep func(*Simulator)
+
+	// Input provides the values read by input builtins. If it's
+	// nil, executing an input builtin is an error.
+	Input Reader
+
+	net      *netlist.Netlist
+	state    []bool
+	time     time.Duration
+	started  bool
+	queue    queue
+	children [][]int
+
+	// read is set whenever an input builtin is executed, so Run can
+	// leave the time spent waiting for it out of the simulation.
+	read bool
+
+	// the values of nodes are cached while a step is evaluated,
+	// and invalidated by incrementing gen.
+	cache []bool
+	stamp []int
+	gen   int
+}
+
+// New elaborates a program and makes a Simulator for it.
+func New(prog *ast.Program) (*Simulator, error) {
+	net, err := netlist.Elaborate(prog)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromNetlist(net), nil
+}
+
+// FromNetlist makes a Simulator for an already elaborated netlist.
+// Every register starts off low.
+func FromNetlist(net *netlist.Netlist) *Simulator {
+	return &Simulator{
+		StepSize: DefaultStepSize,
+		net:      net,
+		state:    make([]bool, len(net.Registers)),
+		cache:    make([]bool, len(net.Nodes)),
+		stamp:    make([]int, len(net.Nodes)),
+	}
+}
+
+// Netlist returns the netlist being simulated.
+func (s *Simulator) Netlist() *netlist.Netlist {
+	return s.net
+}
+
+// Time returns how much simulated time has passed.
+func (s *Simulator) Time() time.Duration {
+	return s.time
+}
+
+// Get returns the value of the register with the given name.
+func (s *Simulator) Get(name string) (bool, error) {
+	id := s.net.Register(name)
+	if id < 0 {
+		return false, fmt.Errorf("no register called '%s'", name)
+	}
+
+	return s.state[id], nil
+}
+
+// Set sets the value of the register with the given name.
+func (s *Simulator) Set(name string, value bool) error {
+	id := s.net.Register(name)
+	if id < 0 {
+		return fmt.Errorf("no register called '%s'", name)
+	}
+
+	s.state[id] = value
+
+	return nil
+}
+
+// Value returns the value of the register at the given index.
+func (s *Simulator) Value(reg int) bool {
+	return s.state[reg]
+}
+
+// SetValue sets the value of the register at the given index.
+func (s *Simulator) SetValue(reg int, value bool) {
+	s.state[reg] = value
+}
+
+// An Output is the current value of a call to an output builtin,
+// such as obit.
+type Output struct {
+	Builtin, Label, Value string
+}
+
+// Outputs returns the current values of the netlist's watches, in
+// the order they were declared in.
+func (s *Simulator) Outputs() []Output {
+	outputs := make([]Output, len(s.net.Watches))
+
+	s.gen++
+
+	for i, watch := range s.net.Watches {
+		bits := make([]bool, len(watch.Nodes))
+		for j, node := range watch.Nodes {
+			bits[j] = s.eval(node)
+		}
+
+		outputs[i].Builtin = watch.Builtin
+		outputs[i].Label = watch.Label
+
+		if b, ok := builtin.Lookup(watch.Builtin); ok {
+			outputs[i].Value = b.Format(bits)
+		}
+	}
+
+	return outputs
+}
+
+// Step advances the simulation by the given duration, firing every
+// clock which is due in the meantime, in the order they are due.
+// The first step also executes the top-level statements of the
+// circuit, so Step(0) can be used to start the simulation without
+// executing any clocks.
+func (s *Simulator) Step(d time.Duration) error {
+	if !s.started {
+		s.started = true
+
+		for i, clock := range s.net.Clocks {
+			if clock.Period <= 0 {
+				return fmt.Errorf("[%s] clock %d has a non-positive period: %s", clock.Range, i, clock.Period)
+			}
+		}
+
+		if err := s.exec(s.net.Init); err != nil {
+			return err
+		}
+
+		s.schedule()
+	}
+
+	target := s.time + d
+
+	for len(s.queue) > 0 && s.queue[0].at <= target {
+		if err := s.fire(); err != nil {
+			return err
+		}
+	}
+
+	s.time = target
+
+	return nil
+}
+
+// Run steps the simulation until the context is cancelled, until
+// Limit is reached, or, if there's no limit, until there are no
+// clocks running. In RealTime mode, the simulation is kept in time
+// with real life, except that simulated time doesn't pass while an
+// input builtin is waiting to be read. In Virtual mode it is run as
+// fast as possible.
+func (s *Simulator) Run(ctx context.Context) error {
+	if err := s.Step(0); err != nil {
+		return err
+	}
+
+	if s.OnStep != nil {
+		s.OnStep(s)
+	}
+
+	var (
+		start = time.Now()
+		from  = s.time
+		timer = time.NewTimer(0)
+	)
+	defer timer.Stop()
+
+	for s.Limit <= 0 || s.time < s.Limit {
+		// without a limit, the simulation stops once there aren't
+		// any clocks left to change anything.
+		if _, ok := s.Next(); !ok && s.Limit <= 0 {
+			return nil
+		}
+
+		d := s.StepSize
+		if d <= 0 {
+			d = DefaultStepSize
+		}
+
+		if next, ok := s.Next(); ok && next-s.time < d {
+			d = next - s.time
+		}
+
+		if s.Limit > 0 && s.time+d > s.Limit {
+			d = s.Limit - s.time
+		}
+
+		if s.Mode == RealTime {
+			// wait until the wall clock catches up with the end of
+			// the step, measured from the start so errors in each
+			// individual wait don't accumulate.
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+
+			timer.Reset(time.Until(start.Add(s.time + d - from)))
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timer.C:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		s.read = false
+
+		if err := s.Step(d); err != nil {
+			return err
+		}
+
+		// an input pauses the simulation, so time starts again from
+		// when it was read.
+		if s.read {
+			start, from = time.Now(), s.time
+		}
+
+		if s.OnStep != nil {
+			s.OnStep(s)
+		}
+	}
+
+	return nil
+}
+
+func (s *Simulator) exec(steps []netlist.Step) error {
+	for _, step := range steps {
+		switch st := step.(type) {
+		case *netlist.Assign:
+			s.gen++
+
+			values := make([]bool, len(st.Sources))
+			for i, src := range st.Sources {
+				values[i] = s.eval(src)
+			}
+
+			for i, reg := range st.Targets {
+				s.state[reg] = values[i]
+			}
+
+		case *netlist.Input:
+			if s.Input == nil {
+				return fmt.Errorf("[%s] cannot read %s: there is no input source", st.Range, st.Label)
+			}
+
+			s.read = true
+
+			values, err := s.Input.Read(st.Label, len(st.Targets))
+			if err != nil {
+				return fmt.Errorf("[%s] %s", st.Range, err)
+			}
+
+			if len(values) != len(st.Targets) {
+				return fmt.Errorf("[%s] read %d bits into %s, which is %d bits wide", st.Range, len(values), st.Label, len(st.Targets))
+			}
+
+			for i, reg := range st.Targets {
+				s.state[reg] = values[i]
+			}
+
+		default:
+			return fmt.Errorf("cannot execute a step of type %T", step)
+		}
+	}
+
+	return nil
+}
+
+// eval evaluates the node at the given index.
+func (s *Simulator) eval(id int) bool {
+	if s.stamp[id] == s.gen {
+		return s.cache[id]
+	}
+
+	var (
+		node  = s.net.Nodes[id]
+		value bool
+	)
+
+	switch node.Op {
+	case netlist.Const:
+		value = node.Value
+
+	case netlist.Read:
+		value = s.state[node.Register]
+
+	case netlist.Not:
+		value = !s.eval(node.Args[0])
+
+	case netlist.And:
+		value = s.eval(node.Args[0]) && s.eval(node.Args[1])
+
+	case netlist.Or:
+		value = s.eval(node.Args[0]) || s.eval(node.Args[1])
+
+	case netlist.Xor:
+		value = s.eval(node.Args[0]) != s.eval(node.Args[1])
+
+	case netlist.Nand:
+		value = !(s.eval(node.Args[0]) && s.eval(node.Args[1]))
+
+	case netlist.Nor:
+		value = !(s.eval(node.Args[0]) || s.eval(node.Args[1]))
+
+	case netlist.Xnor:
+		value = s.eval(node.Args[0]) == s.eval(node.Args[1])
+
+	case netlist.Imp:
+		value = !s.eval(node.Args[0]) || s.eval(node.Args[1])
+	}
+
+	s.cache[id] = value
+	s.stamp[id] = s.gen
+
+	return value
+}
@@ -0,0 +1,561 @@
+package sim_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zac-garby/booleang/parser"
+	"github.com/zac-garby/booleang/prompt"
+	. "github.com/zac-garby/booleang/sim"
+)
+
+func simulate(t *testing.T, input string) *Simulator {
+	prog, err := parser.New(`name: "test";`+input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func expect(t *testing.T, s *Simulator, regs map[string]bool) {
+	t.Helper()
+
+	for name, exp := range regs {
+		got, err := s.Get(name)
+		if err != nil {
+			t.Error(err)
+		} else if got != exp {
+			t.Errorf("[%s] expected %s to be %v, got %v", s.Time(), name, exp, got)
+		}
+	}
+}
+
+func TestAdder(t *testing.T) {
+	s := simulate(t, `
+circuit adder (a, b, cin) -> (sum, cout) {
+	((a ^ b) ^ cin) -> sum;
+	(((a ^ b) & cin) | (a & b)) -> cout;
+}
+
+circuit main {
+	adder (1, 1, 0) -> (s0, c0);
+	adder (0, 0, c0) -> (s1, c1);
+	(s1, s0) -> (s0, s1);
+}
+`)
+
+	if err := s.Step(0); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, s, map[string]bool{
+		"s0": true, "c0": true,
+		"s1": false, "c1": false,
+	})
+}
+
+func TestClock(t *testing.T) {
+	s := simulate(t, `
+circuit main {
+	0 -> a;
+	0 -> b;
+
+	clock 1s {
+		!a -> a;
+	}
+
+	clock 2s {
+		!b -> b;
+	}
+}
+`)
+
+	if err := s.Step(0); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, s, map[string]bool{"a": false, "b": false})
+
+	if err := s.Step(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, s, map[string]bool{"a": true, "b": false})
+
+	if err := s.Step(3 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, s, map[string]bool{"a": false, "b": false})
+}
+
+func TestNestedClocks(t *testing.T) {
+	s := simulate(t, `
+circuit main {
+	clock 1s {
+		1 -> outer;
+
+		clock 300ms {
+			!inner -> inner;
+		}
+	}
+}
+`)
+
+	s.Mode = Virtual
+	s.StepSize = time.Hour
+	s.Limit = 1500 * time.Millisecond
+
+	steps := 0
+	s.OnStep = func(*Simulator) {
+		steps++
+	}
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Time() != s.Limit {
+		t.Errorf("expected to stop at %s, stopped at %s", s.Limit, s.Time())
+	}
+
+	// the inner clock starts when the outer one first fires, at 1s,
+	// so it has ticked at 1.3s but not yet at 1.6s.
+	expect(t, s, map[string]bool{"outer": true, "inner": true})
+
+	// once for the top-level statements, then at 1s, 1.3s and 1.5s
+	if steps != 4 {
+		t.Errorf("expected 4 steps, got %d", steps)
+	}
+}
+
+func TestCounter(t *testing.T) {
+	s := simulate(t, `
+circuit main {
+	tick[3];
+	(1, 1, 1) -> tick;
+
+	clock 1s %tick[3] {
+		(%tick) -> (b0, b1, b2);
+	}
+}
+`)
+
+	// the body sees the count before it's incremented, so after 6
+	// ticks it has seen 0 to 5, and the counter itself holds 6.
+	if err := s.Step(6 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, s, map[string]bool{
+		"b0": true, "b1": false, "b2": true,
+		"clock#0.%tick[0]": false, "clock#0.%tick[1]": true, "clock#0.%tick[2]": true,
+	})
+
+	// after 9 ticks it has wrapped around, and holds 1.
+	if err := s.Step(3 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, s, map[string]bool{
+		"clock#0.%tick[0]": true, "clock#0.%tick[1]": false, "clock#0.%tick[2]": false,
+
+		// the bus called tick is separate from the counter.
+		"tick[0]": true, "tick[1]": true, "tick[2]": true,
+	})
+}
+
+func TestClockBuses(t *testing.T) {
+	s := simulate(t, `
+circuit main {
+	# each clock has its own bus called t.
+	clock 1s {
+		t[2];
+		(1, 0) -> t;
+		(t[0]) -> a;
+	}
+
+	clock 1s {
+		t[2];
+		(0, 1) -> t;
+		(t[1]) -> b;
+	}
+}
+`)
+
+	if err := s.Step(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, s, map[string]bool{"a": true, "b": true})
+}
+
+func TestSeparateCounters(t *testing.T) {
+	s := simulate(t, `
+circuit main {
+	clock 1s %tick[2] {
+		(%tick) -> (a0, a1);
+	}
+
+	clock 1s %tick[2] {
+		(%tick) -> (b0, b1);
+	}
+
+	for i in 0..2 {
+		clock 1s %n[2] {
+			(%n) -> (c<i>, d<i>);
+		}
+	}
+}
+`)
+
+	// after 3 ticks, every counter has seen 0, 1 and 2.
+	if err := s.Step(3 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, s, map[string]bool{
+		"a0": false, "a1": true,
+		"b0": false, "b1": true,
+		"c0": false, "d0": true,
+		"c1": false, "d1": true,
+	})
+}
+
+func TestOutputs(t *testing.T) {
+	s := simulate(t, `
+circuit main {
+	%n (a, b, c);
+	(1, 0, 1) -> %n;
+	obit (%n);
+	onumu (%n);
+	onums (%n);
+}
+`)
+
+	if err := s.Step(0); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Output{
+		{"obit", "%n", "1 0 1"},
+		{"onumu", "%n", "5"},
+		{"onums", "%n", "-3"},
+	}
+
+	outputs := s.Outputs()
+	if len(outputs) != len(expected) {
+		t.Fatalf("expected %d outputs, got %d", len(expected), len(outputs))
+	}
+
+	for i, exp := range expected {
+		if outputs[i] != exp {
+			t.Errorf("(%d) expected %v, got %v", i, exp, outputs[i])
+		}
+	}
+}
+
+func TestInput(t *testing.T) {
+	s := simulate(t, `
+circuit main {
+	%n (a, b, c);
+	input () -> (%n);
+
+	clock 1s {
+		input () -> (d);
+	}
+}
+`)
+
+	s.Input = prompt.NewScript(strings.NewReader("0b110\n1\n"), "test")
+
+	if err := s.Step(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, s, map[string]bool{"a": false, "b": true, "c": true, "d": true})
+
+	if err := s.Step(time.Second); err == nil {
+		t.Errorf("expected an error once the inputs run out")
+	}
+}
+
+func TestRunWithoutClocks(t *testing.T) {
+	s := simulate(t, `
+circuit main {
+	1 -> x;
+}
+`)
+
+	s.Mode = Virtual
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := s.Run(ctx); err != nil {
+		t.Fatalf("expected the run to stop on its own, got %v", err)
+	}
+
+	expect(t, s, map[string]bool{"x": true})
+}
+
+// slowReader takes a while to read its first value, like someone
+// typing into a prompt.
+type slowReader struct {
+	delay time.Duration
+	reads int
+}
+
+func (r *slowReader) Read(label string, width int) ([]bool, error) {
+	if r.reads == 0 {
+		time.Sleep(r.delay)
+	}
+
+	r.reads++
+
+	return make([]bool, width), nil
+}
+
+func TestInputPauses(t *testing.T) {
+	s := simulate(t, `
+circuit main {
+	clock 50ms {
+		input () -> (d);
+	}
+}
+`)
+
+	s.Input = &slowReader{delay: 200 * time.Millisecond}
+	s.Mode = RealTime
+	s.Limit = 250 * time.Millisecond
+
+	start := time.Now()
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the ticks after the slow input should still be 50ms apart, so
+	// the whole run takes 250ms plus the time spent waiting.
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("expected the run to take about 450ms, but it took %s", elapsed)
+	}
+}
+
+func TestGates(t *testing.T) {
+	s := simulate(t, `
+circuit gates (a, b) -> (nand, nor, xnor, imp) {
+	(a !& b, a !| b, a !^ b, a => b) -> (nand, nor, xnor, imp);
+}
+
+circuit main {
+	gates (0, 0) -> (nand00, nor00, xnor00, imp00);
+	gates (0, 1) -> (nand01, nor01, xnor01, imp01);
+	gates (1, 0) -> (nand10, nor10, xnor10, imp10);
+	(1 ⊼ 1, 1 ⊽ 1, 1 ≡ 1, 1 → 1, 0 ↔ 1) -> (nand11, nor11, xnor11, imp11, equiv);
+}
+`)
+
+	if err := s.Step(0); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, s, map[string]bool{
+		"nand00": true, "nor00": true, "xnor00": true, "imp00": true,
+		"nand01": true, "nor01": false, "xnor01": false, "imp01": true,
+		"nand10": true, "nor10": false, "xnor10": false, "imp10": false,
+		"nand11": false, "nor11": false, "xnor11": true, "imp11": true,
+		"equiv": false,
+	})
+}
+
+func TestBuses(t *testing.T) {
+	s := simulate(t, `
+circuit swap (a[4]) -> (b[4]) {
+	(a[3:2], a[1:0]) -> b;
+}
+
+circuit main {
+	x[4];
+	y[4];
+	z[8];
+
+	(1, 0, 0, 1) -> x;
+	swap (x) -> (y);
+	{y, x[0:3]} -> z;
+	(z[7] ^ z[6]) -> p;
+}
+`)
+
+	if err := s.Step(0); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, s, map[string]bool{
+		"y[0]": false, "y[1]": true, "y[2]": true, "y[3]": false,
+		"z[0]": false, "z[1]": true, "z[2]": true, "z[3]": false,
+		"z[4]": true, "z[5]": false, "z[6]": false, "z[7]": true,
+		"p": true,
+	})
+}
+
+func TestBitwise(t *testing.T) {
+	s := simulate(t, `
+circuit main {
+	%a (a0, a1, a2, a3);
+	%y (y0, y1, y2, y3);
+	b[4];
+	x[4];
+
+	(1, 1, 0, 0) -> %a;
+	(1, 0, 1, 0) -> b;
+
+	(%a & b) -> x[3:0];
+	(!%a ^ 1) -> %y;
+	(&%a, |%a, ^b, !|{b[3], a3}, &(%a | b)) -> (and, or, parity, zero, all);
+	(!^b, ↔b, ≡{b[0], a2}) -> (even, equiv, odd);
+}
+`)
+
+	if err := s.Step(0); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, s, map[string]bool{
+		"x[0]": true, "x[1]": false, "x[2]": false, "x[3]": false,
+		"y0": true, "y1": true, "y2": false, "y3": false,
+		"and": false, "or": true, "parity": false, "zero": true, "all": false,
+		"even": true, "equiv": true, "odd": false,
+	})
+}
+
+func TestNumbers(t *testing.T) {
+	s := simulate(t, `
+circuit main {
+	%a (a0, a1, a2, a3);
+	b[8];
+	c[4];
+	d[4];
+	e[4];
+
+	9 -> %a;
+	8'hA5 -> b;
+	-2 -> c;
+	(%a ^ 4'b1111, 6 & c) -> (d[3:0], e[3:0]);
+}
+`)
+
+	if err := s.Step(0); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, s, map[string]bool{
+		"a0": true, "a1": false, "a2": false, "a3": true,
+		"b[0]": true, "b[1]": false, "b[2]": true, "b[5]": true, "b[7]": true,
+		"c[0]": false, "c[1]": true, "c[2]": true, "c[3]": true,
+		"d[0]": false, "d[1]": true, "d[2]": true, "d[3]": false,
+		"e[0]": false, "e[1]": true, "e[2]": true, "e[3]": false,
+	})
+}
+
+func TestGenerics(t *testing.T) {
+	s := simulate(t, `
+circuit mux<N> (sel, a[N], b[N]) -> (out[N]) {
+	((a & !sel) | (b & sel)) -> out;
+}
+
+circuit widen<N> (a[N]) -> (b[N*2+1]) {
+	{a, a, 1} -> b;
+}
+
+circuit main {
+	x[2];
+	y[3];
+
+	mux<2> (1, 2'b01, 2'b10) -> (x);
+	widen<1> (x[1]) -> (y);
+}
+`)
+
+	if err := s.Step(0); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, s, map[string]bool{
+		"x[0]": false, "x[1]": true,
+		"y[0]": true, "y[1]": true, "y[2]": true,
+	})
+}
+
+func TestLoops(t *testing.T) {
+	s := simulate(t, `
+circuit adder (a, b, cin) -> (sum, cout) {
+	((a ^ b) ^ cin) -> sum;
+	(((a ^ b) & cin) | (a & b)) -> cout;
+}
+
+circuit addN<N> (a[N], b[N]) -> (s[N], carry) {
+	0 -> c0;
+
+	for i in 0..N {
+		adder (a[i], b[i], c<i>) -> (s[i], c<i+1>);
+	}
+
+	(c<N>) -> carry;
+}
+
+circuit main {
+	x[8];
+	shift[4];
+
+	addN<8> (8'd200, 8'd100) -> (x, carry);
+
+	1 -> shift[0];
+
+	clock 1s {
+		# each statement in the loop happens after the last, so
+		# the bits are shifted from the top down.
+		for i in 0..3 {
+			(shift[2-i]) -> shift[3-i];
+		}
+	}
+}
+`)
+
+	if err := s.Step(2 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// 200 + 100 = 300 = 256 + 44
+	expect(t, s, map[string]bool{
+		"x[0]": false, "x[1]": false, "x[2]": true, "x[3]": true,
+		"x[4]": false, "x[5]": true, "x[6]": false, "x[7]": false,
+		"carry":    true,
+		"shift[1]": true, "shift[2]": true, "shift[3]": false,
+	})
+}
+
+func TestLoopBuses(t *testing.T) {
+	s := simulate(t, `
+circuit main {
+	x[3];
+	y[3];
+	(0, 1, 0) -> x;
+
+	# t is declared again in each iteration.
+	for i in 0..3 {
+		t[2];
+		(1, x[i]) -> t;
+		(t[0] & t[1]) -> y[i];
+	}
+}
+`)
+
+	if err := s.Step(0); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, s, map[string]bool{"y[0]": false, "y[1]": true, "y[2]": false})
+}
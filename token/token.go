@@ -13,26 +13,36 @@ const (
 	Illegal = "illegal"
 
 	Number = "number"
+	Sized  = "sized-number"
 	Ident  = "identifier"
 	String = "string"
 
 	Prefix = "prefix"
 	Infix  = "infix"
 
-	Semi       = "semi"
-	LeftParen  = "left-paren"
-	RightParen = "right-paren"
-	LeftBrace  = "left-brace"
-	RightBrace = "right-brace"
-	Comma      = "comma"
-	Macro      = "macro"
-	Arrow      = "arrow"
-	Colon      = "colon"
+	Semi         = "semi"
+	LeftParen    = "left-paren"
+	RightParen   = "right-paren"
+	LeftBrace    = "left-brace"
+	RightBrace   = "right-brace"
+	LeftBracket  = "left-bracket"
+	RightBracket = "right-bracket"
+	Comma        = "comma"
+	Macro        = "macro"
+	Arrow        = "arrow"
+	Colon        = "colon"
+	LeftAngle    = "left-angle"
+	RightAngle   = "right-angle"
+	Plus         = "plus"
+	Minus        = "minus"
+	Star         = "star"
+	DotDot       = "dot-dot"
 
 	Clock   = "clock"
 	Name    = "name"
 	Circuit = "circuit"
 	Include = "include"
+	For     = "for"
 )
 
 // Keywords maps keyword literals to their types.
@@ -41,6 +51,7 @@ var Keywords = map[string]Type{
 	"name":    Name,
 	"circuit": Circuit,
 	"include": Include,
+	"for":     For,
 }
 
 // IsKeyword checks whether or not a Type is a keyword.
@@ -64,12 +75,8 @@ type Token struct {
 
 func (t *Token) String() string {
 	return fmt.Sprintf(
-		"[%s %d:%d-%d:%d] %s `%s`",
-		t.Range.Start.File,
-		t.Range.Start.Line,
-		t.Range.Start.Col,
-		t.Range.End.Line,
-		t.Range.End.Col,
+		"[%s] %s `%s`",
+		t.Range,
 		t.Type,
 		t.Literal,
 	)
@@ -85,3 +92,16 @@ type Position struct {
 type Range struct {
 	Start, End Position
 }
+
+// String returns the range in the form file:line:col-line:col, which
+// editors and other tools can jump to.
+func (r Range) String() string {
+	return fmt.Sprintf(
+		"%s:%d:%d-%d:%d",
+		r.Start.File,
+		r.Start.Line,
+		r.Start.Col,
+		r.End.Line,
+		r.End.Col,
+	)
+}
@@ -0,0 +1,18 @@
+package token_test
+
+import (
+	"testing"
+
+	. "github.com/zac-garby/booleang/token"
+)
+
+func TestRangeString(t *testing.T) {
+	r := Range{
+		Start: Position{Line: 3, Col: 5, File: "adder.bl"},
+		End:   Position{Line: 4, Col: 1, File: "adder.bl"},
+	}
+
+	if got := r.String(); got != "adder.bl:3:5-4:1" {
+		t.Errorf("expected adder.bl:3:5-4:1, got %s", got)
+	}
+}
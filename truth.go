@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/zac-garby/booleang/netlist"
+	"github.com/zac-garby/booleang/truth"
+)
+
+// truthCommand prints the truth table of a circuit:
+//
+//	bl truth [flags] <file> <circuit>
+//
+// A generic circuit's parameters are given after its name, e.g.
+// addN<4>.
+func truthCommand(args []string) {
+	fs := flag.NewFlagSet("truth", flag.ExitOnError)
+
+	var (
+		format = fs.String("format", "text", "the format of the table: text, markdown or csv")
+		max    = fs.Int("max", truth.DefaultMaxInputs, "the most input bits a circuit can have before its table is refused or sampled")
+		sample = fs.Int("sample", 0, "if a circuit has too many input bits, print this many rows picked at random instead")
+		seed   = fs.Int64("seed", 0, "the seed used to pick random rows")
+	)
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: bl truth [flags] <file> <circuit>")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	f, err := truth.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	table, err := truth.Generate(elaborate(fs.Arg(0), fs.Arg(1)), truth.Options{
+		MaxInputs: *max,
+		Sample:    *sample,
+		Seed:      *seed,
+	})
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := table.Write(os.Stdout, f); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// elaborate builds the netlist of the named circuit in a file, and
+// exits if it can't.
+func elaborate(path, circuit string) *netlist.Netlist {
+	name, generics, err := parseCircuitName(circuit)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	prog := load(path, false)
+
+	net, err := netlist.ElaborateCircuit(prog, name, generics...)
+	if err != nil {
+		report(err)
+		os.Exit(1)
+	}
+
+	return net
+}
+
+// parseCircuitName splits a circuit's name from the values of its
+// generic parameters, e.g. addN<4> into addN and [4].
+func parseCircuitName(s string) (string, []int, error) {
+	open := strings.IndexByte(s, '<')
+	if open < 0 {
+		return s, nil, nil
+	}
+
+	if !strings.HasSuffix(s, ">") {
+		return "", nil, fmt.Errorf("expected a '>' at the end of %s", s)
+	}
+
+	var generics []int
+
+	for _, arg := range strings.Split(s[open+1:len(s)-1], ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(arg))
+		if err != nil {
+			return "", nil, fmt.Errorf("the generic parameters of %s must be integers", s)
+		}
+
+		generics = append(generics, n)
+	}
+
+	return s[:open], generics, nil
+}
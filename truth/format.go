@@ -0,0 +1,163 @@
+package truth
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A Format is a way of writing a table.
+type Format int
+
+// The formats a table can be written in.
+const (
+	// Text aligns the columns with spaces, and separates the inputs
+	// from the outputs with a vertical bar.
+	Text Format = iota
+
+	// Markdown writes a table which can be put in a Markdown file.
+	Markdown
+
+	// CSV writes comma-separated values, with the column names in
+	// the first row.
+	CSV
+)
+
+var formatNames = map[string]Format{
+	"text":     Text,
+	"markdown": Markdown,
+	"md":       Markdown,
+	"csv":      CSV,
+}
+
+// ParseFormat finds the format with the given name, which is one of
+// text, markdown (or md) and csv.
+func ParseFormat(name string) (Format, error) {
+	if f, ok := formatNames[strings.ToLower(name)]; ok {
+		return f, nil
+	}
+
+	return Text, fmt.Errorf("unknown format '%s'. expected text, markdown or csv", name)
+}
+
+// Write writes the table to w in the given format.
+func (t *Table) Write(w io.Writer, f Format) error {
+	switch f {
+	case Markdown:
+		return t.markdown(w)
+	case CSV:
+		return t.csv(w)
+	}
+
+	return t.text(w)
+}
+
+func (t *Table) text(w io.Writer) error {
+	widths := make([]int, len(t.Inputs)+len(t.Outputs))
+	for i, name := range t.columns() {
+		widths[i] = len(name)
+	}
+
+	line := func(cells []string) error {
+		var b strings.Builder
+
+		for i, cell := range cells {
+			if i == len(t.Inputs) {
+				b.WriteString("| ")
+			}
+
+			fmt.Fprintf(&b, "%-*s ", widths[i], cell)
+		}
+
+		_, err := fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+		return err
+	}
+
+	if err := line(t.columns()); err != nil {
+		return err
+	}
+
+	for _, row := range t.Rows {
+		if err := line(row.cells()); err != nil {
+			return err
+		}
+	}
+
+	return t.footer(w)
+}
+
+func (t *Table) markdown(w io.Writer) error {
+	line := func(cells []string) error {
+		_, err := fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+		return err
+	}
+
+	if err := line(t.columns()); err != nil {
+		return err
+	}
+
+	rule := make([]string, len(t.Inputs)+len(t.Outputs))
+	for i := range rule {
+		rule[i] = ":-:"
+	}
+
+	if err := line(rule); err != nil {
+		return err
+	}
+
+	for _, row := range t.Rows {
+		if err := line(row.cells()); err != nil {
+			return err
+		}
+	}
+
+	return t.footer(w)
+}
+
+func (t *Table) csv(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(t.columns()); err != nil {
+		return err
+	}
+
+	for _, row := range t.Rows {
+		if err := cw.Write(row.cells()); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// footer notes that a table was sampled, so it isn't mistaken for
+// the whole thing. CSV doesn't have one, so it can still be parsed.
+func (t *Table) footer(w io.Writer) error {
+	if !t.Sampled {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "\n(%d rows sampled at random from %d input bits)\n", len(t.Rows), len(t.Inputs))
+	return err
+}
+
+func (t *Table) columns() []string {
+	return append(append([]string{}, t.Inputs...), t.Outputs...)
+}
+
+func (r Row) cells() []string {
+	var cells []string
+
+	for _, bit := range append(append([]bool{}, r.Inputs...), r.Outputs...) {
+		if bit {
+			cells = append(cells, "1")
+		} else {
+			cells = append(cells, "0")
+		}
+	}
+
+	return cells
+}
@@ -0,0 +1,201 @@
+package truth
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/zac-garby/booleang/netlist"
+	"github.com/zac-garby/booleang/sim"
+)
+
+// DefaultMaxInputs is the largest number of input bits a circuit can
+// have before its table is refused or sampled, if Options doesn't
+// say otherwise. A circuit with this many inputs has 65536 rows.
+const DefaultMaxInputs = 16
+
+// Options control how a table is generated.
+type Options struct {
+	// MaxInputs is the largest number of input bits a circuit can
+	// have and still have every row of its table generated. If it's
+	// 0, DefaultMaxInputs is used.
+	MaxInputs int
+
+	// Sample is the number of rows to pick at random from the table
+	// of a circuit with more than MaxInputs inputs. If it's 0, the
+	// table isn't generated at all.
+	Sample int
+
+	// Seed seeds the random rows picked when sampling.
+	Seed int64
+}
+
+// A Table is the truth table of a circuit. Each column is named
+// after the register it refers to, e.g. a[0] for the least
+// significant bit of a bus a.
+type Table struct {
+	Inputs, Outputs []string
+	Rows            []Row
+
+	// Sampled is set if the rows were picked at random, instead of
+	// being every combination of the inputs.
+	Sampled bool
+}
+
+// A Row is one combination of inputs, and the outputs they produce.
+type Row struct {
+	Inputs, Outputs []bool
+}
+
+// Generate builds the truth table of a netlist by simulating it once
+// for every combination of its inputs. The netlist must be purely
+// combinational, so it can't contain any clocks or inputs builtins.
+func Generate(net *netlist.Netlist, opts Options) (*Table, error) {
+	if len(net.Clocks) > 0 {
+		return nil, fmt.Errorf(
+			"circuit '%s' contains a clock at %s, so it doesn't have a truth table",
+			net.Circuit, net.Clocks[0].Range,
+		)
+	}
+
+	for _, step := range net.Init {
+		if in, ok := step.(*netlist.Input); ok {
+			return nil, fmt.Errorf(
+				"circuit '%s' reads an input at %s, so it doesn't have a truth table",
+				net.Circuit, in.Range,
+			)
+		}
+	}
+
+	table := &Table{}
+
+	for _, reg := range net.Inputs {
+		table.Inputs = append(table.Inputs, net.Registers[reg].Name)
+	}
+
+	for _, reg := range net.Outputs {
+		table.Outputs = append(table.Outputs, net.Registers[reg].Name)
+	}
+
+	max := opts.MaxInputs
+	if max == 0 {
+		max = DefaultMaxInputs
+	}
+
+	n := len(net.Inputs)
+
+	var combinations [][]bool
+
+	switch {
+	case n <= max:
+		for i := 0; i < 1<<uint(n); i++ {
+			combinations = append(combinations, combination(i, n))
+		}
+
+	case opts.Sample > 0:
+		combinations = sample(n, opts.Sample, opts.Seed)
+		table.Sampled = true
+
+	default:
+		return nil, fmt.Errorf(
+			"circuit '%s' has %d input bits, so its truth table would have 2^%d rows. the limit is %d input bits",
+			net.Circuit, n, n, max,
+		)
+	}
+
+	for _, inputs := range combinations {
+		row, err := evaluate(net, inputs)
+		if err != nil {
+			return nil, err
+		}
+
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
+
+// combination returns the ith combination of n inputs. The first
+// input is the most significant, so the rows are in the usual order.
+func combination(i, n int) []bool {
+	bits := make([]bool, n)
+
+	for j := range bits {
+		bits[j] = i&(1<<uint(n-j-1)) != 0
+	}
+
+	return bits
+}
+
+// sample picks up to count different combinations of n inputs at
+// random, in the same order they'd appear in the full table.
+func sample(n, count int, seed int64) [][]bool {
+	var (
+		rng  = rand.New(rand.NewSource(seed))
+		seen = make(map[string]bool)
+		rows [][]bool
+		keys []string
+	)
+
+	// give up on finding new combinations eventually, in case there
+	// are fewer than count of them.
+	for tries := 0; len(rows) < count && tries < count*4; tries++ {
+		bits := make([]bool, n)
+		key := make([]byte, n)
+
+		for i := range bits {
+			bits[i] = rng.Intn(2) == 1
+			key[i] = '0'
+			if bits[i] {
+				key[i] = '1'
+			}
+		}
+
+		if seen[string(key)] {
+			continue
+		}
+
+		seen[string(key)] = true
+		rows = append(rows, bits)
+		keys = append(keys, string(key))
+	}
+
+	sort.Sort(byKey{rows, keys})
+
+	return rows
+}
+
+type byKey struct {
+	rows [][]bool
+	keys []string
+}
+
+func (b byKey) Len() int           { return len(b.rows) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.rows[i], b.rows[j] = b.rows[j], b.rows[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+func evaluate(net *netlist.Netlist, inputs []bool) (Row, error) {
+	s := sim.FromNetlist(net)
+
+	for i, reg := range net.Inputs {
+		s.SetValue(reg, inputs[i])
+	}
+
+	if err := s.Step(0); err != nil {
+		return Row{}, err
+	}
+
+	row := Row{
+		Inputs:  inputs,
+		Outputs: make([]bool, len(net.Outputs)),
+	}
+
+	for i, reg := range net.Outputs {
+		row.Outputs[i] = s.Value(reg)
+	}
+
+	return row, nil
+}
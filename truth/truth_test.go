@@ -0,0 +1,97 @@
+package truth_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zac-garby/booleang/netlist"
+	"github.com/zac-garby/booleang/parser"
+	. "github.com/zac-garby/booleang/truth"
+)
+
+const input = `
+circuit adder (a, b, cin) -> (sum, cout) {
+	((a ^ b) ^ cin) -> sum;
+	(((a ^ b) & cin) | (a & b)) -> cout;
+}
+
+circuit wide (a[20]) -> (b) {
+	(^a) -> b;
+}
+
+circuit ticker {
+	clock 1s { !x -> x; }
+}
+`
+
+func generate(t *testing.T, circuit string, opts Options) (*Table, error) {
+	prog, err := parser.New(input, "test").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	net, err := netlist.ElaborateCircuit(prog, circuit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Generate(net, opts)
+}
+
+func TestAdder(t *testing.T) {
+	table, err := generate(t, "adder", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := table.Write(&buf, CSV); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `a,b,cin,sum,cout
+0,0,0,0,0
+0,0,1,1,0
+0,1,0,1,0
+0,1,1,0,1
+1,0,0,1,0
+1,0,1,0,1
+1,1,0,0,1
+1,1,1,1,1
+`
+
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestLimits(t *testing.T) {
+	if _, err := generate(t, "wide", Options{}); err == nil || !strings.Contains(err.Error(), "has 20 input bits") {
+		t.Errorf("expected an error about too many inputs, got %v", err)
+	}
+
+	table, err := generate(t, "wide", Options{Sample: 10, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !table.Sampled || len(table.Rows) != 10 {
+		t.Errorf("expected 10 sampled rows, got %d", len(table.Rows))
+	}
+
+	for _, row := range table.Rows {
+		parity := false
+		for _, bit := range row.Inputs {
+			parity = parity != bit
+		}
+
+		if row.Outputs[0] != parity {
+			t.Errorf("wrong parity for %v", row.Inputs)
+		}
+	}
+
+	if _, err := generate(t, "ticker", Options{}); err == nil || !strings.Contains(err.Error(), "contains a clock") {
+		t.Errorf("expected an error about a clock, got %v", err)
+	}
+}